@@ -1,23 +1,116 @@
 package main
 
 import (
+	"bufio"
 	"log"
 	"net/http"
 	"os"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+
 	"github.com/akikareha/himewiki/internal/action"
 	"github.com/akikareha/himewiki/internal/config"
 	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
 )
 
+func usage() {
+	print("Usage: " + os.Args[0] + " himewiki.yaml\n")
+	print("       " + os.Args[0] + " himewiki.yaml convert PAGE nomark|creole|markdown\n")
+	print("       " + os.Args[0] + " himewiki.yaml adduser NAME < password.txt\n")
+	print("       " + os.Args[0] + " himewiki.yaml setrole NAME reader|editor|moderator|admin\n")
+}
+
+// convert converts page into specified format and saves it.
+func convert(cfg *config.Config, name string, to string) {
+	revisionID, content, err := data.Load(name)
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", name, err)
+	}
+
+	converted, err := format.Convert(cfg, name, content, to)
+	if err != nil {
+		log.Fatalf("Failed to convert %s: %v", name, err)
+	}
+
+	_, err = data.Save(cfg, name, converted, action.PageLinks(cfg, converted), revisionID, data.Meta{
+		Summary: "Converted to " + to,
+		Source:  data.SourceHuman,
+	})
+	if err != nil {
+		log.Fatalf("Failed to save %s: %v", name, err)
+	}
+}
+
+// addUser creates user account with password read from stdin.
+func addUser(name string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		log.Fatalf("Failed to read password of %s", name)
+	}
+	password := scanner.Text()
+	if password == "" {
+		log.Fatalf("Empty password for %s", name)
+	}
+
+	if err := data.CreateUser(name, password); err != nil {
+		log.Fatalf("Failed to add user %s: %v", name, err)
+	}
+}
+
+// setRole changes role of user.
+func setRole(name string, roleName string) {
+	role, ok := data.ParseRole(roleName)
+	if !ok {
+		log.Fatalf("Unknown role %s", roleName)
+	}
+
+	if err := data.SetUserRole(name, role); err != nil {
+		log.Fatalf("Failed to set role of %s: %v", name, err)
+	}
+}
+
+// connect connects to database and fills links table if empty.
+func connect(cfg *config.Config) *pgxpool.Pool {
+	db := data.Connect(cfg)
+	err := data.RebuildLinks(func(content string) []data.Link {
+		return action.PageLinks(cfg, content)
+	})
+	if err != nil {
+		log.Fatalf("Failed to rebuild links: %v", err)
+	}
+	return db
+}
+
 func main() {
 	if len(os.Args) < 2 {
-		print("Usage: " + os.Args[0] + " himewiki.yaml\n")
+		usage()
 		return
 	}
 	cfg := config.Load(os.Args[1])
+	cfg.PageExists = data.PagesExist
 
-	db := data.Connect(cfg)
+	if len(os.Args) > 2 {
+		switch {
+		case os.Args[2] == "convert" && len(os.Args) == 5:
+			db := connect(cfg)
+			defer db.Close()
+			convert(cfg, os.Args[3], os.Args[4])
+		case os.Args[2] == "adduser" && len(os.Args) == 4:
+			db := connect(cfg)
+			defer db.Close()
+			addUser(os.Args[3])
+		case os.Args[2] == "setrole" && len(os.Args) == 5:
+			db := connect(cfg)
+			defer db.Close()
+			setRole(os.Args[3], os.Args[4])
+		default:
+			usage()
+		}
+		return
+	}
+
+	db := connect(cfg)
 	defer db.Close()
 
 	http.HandleFunc("/", action.Handler(cfg))
@@ -0,0 +1,240 @@
+package action
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/templates"
+)
+
+const (
+	sessionCookie      = "session"
+	defaultSessionDays = 30
+	maxUserNameLength  = 64
+	minPasswordLength  = 8
+)
+
+// sessionUser returns name of user signed in by session cookie.
+func sessionUser(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	name, err := data.SessionUser(cookie.Value)
+	if err != nil {
+		log.Println("failed to load session:", err)
+		return ""
+	}
+	return name
+}
+
+func sessionTTL(cfg *config.Config) time.Duration {
+	days := cfg.Auth.SessionDays
+	if days < 1 {
+		days = defaultSessionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// setSessionCookie sets cookie of session token.
+// Empty token clears the cookie.
+func setSessionCookie(cfg *config.Config, w http.ResponseWriter, token string, ttl time.Duration) {
+	cookie := &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.Site.Base, "https:"),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl / time.Second)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// returnPath returns local path to go back after sign in.
+func returnPath(r *http.Request) string {
+	return localPath(r.FormValue("r"))
+}
+
+// localPath returns path if it stays on this site, or "/".
+// Browsers drop tabs and newlines in URL, so paths with
+// control characters are refused too.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") ||
+		strings.HasPrefix(path, "/\\") ||
+		strings.IndexFunc(path, unicode.IsControl) >= 0 {
+		return "/"
+	}
+	return path
+}
+
+// validUserName checks user name has no control characters,
+// no slashes and no surrounding spaces.
+func validUserName(name string) bool {
+	if name == "" || !utf8.ValidString(name) ||
+		utf8.RuneCountInString(name) > maxUserNameLength {
+		return false
+	}
+	if strings.TrimSpace(name) != name {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '/' {
+			return false
+		}
+	}
+	return true
+}
+
+func renderLogin(cfg *config.Config, w http.ResponseWriter, r *http.Request, name string, returnTo string, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	data := struct {
+		SiteName string
+		Name     string
+		Return   string
+		Signup   bool
+		SSO      bool
+		Message  string
+		CSRF     string
+	}{
+		SiteName: cfg.Site.Name,
+		Name:     name,
+		Return:   returnTo,
+		Signup:   cfg.Auth.Signup,
+		SSO:      cfg.OIDC.Issuer != "",
+		Message:  message,
+		CSRF:     csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "login", data)
+}
+
+// Login signs in user and starts session.
+func Login(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		renderLogin(cfg, w, r, "", returnPath(r), "")
+		return
+	}
+
+	name := norm.NFC.String(r.FormValue("name"))
+	password := r.FormValue("password")
+	err := data.Authenticate(name, password)
+	if errors.Is(err, data.ErrLogin) {
+		renderLogin(cfg, w, r, name, returnPath(r), "Wrong user name or password.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	ttl := sessionTTL(cfg)
+	token, err := data.CreateSession(name, ttl)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(cfg, w, token, ttl)
+
+	http.Redirect(w, r, returnPath(r), http.StatusFound)
+}
+
+// Logout ends session of user.
+func Logout(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+		if err := data.DeleteSession(cookie.Value); err != nil {
+			http.Error(w, "Failed to end session", http.StatusInternalServerError)
+			return
+		}
+	}
+	setSessionCookie(cfg, w, "", 0)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func renderSignup(cfg *config.Config, w http.ResponseWriter, r *http.Request, name string, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	data := struct {
+		SiteName          string
+		Name              string
+		Message           string
+		MinPasswordLength int
+		CSRF              string
+	}{
+		SiteName:          cfg.Site.Name,
+		Name:              name,
+		Message:           message,
+		MinPasswordLength: minPasswordLength,
+		CSRF:              csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "signup", data)
+}
+
+// Signup creates user account and signs in.
+func Signup(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if !cfg.Auth.Signup {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		renderSignup(cfg, w, r, "", "")
+		return
+	}
+
+	name := norm.NFC.String(r.FormValue("name"))
+	password := r.FormValue("password")
+	if !validUserName(name) {
+		renderSignup(cfg, w, r, name, "Invalid user name.")
+		return
+	}
+	if len(password) < minPasswordLength {
+		renderSignup(cfg, w, r, name, "Password is too short.")
+		return
+	}
+	if password != r.FormValue("confirm") {
+		renderSignup(cfg, w, r, name, "Passwords do not match.")
+		return
+	}
+
+	err := data.CreateUser(name, password)
+	if errors.Is(err, data.ErrUserExists) {
+		renderSignup(cfg, w, r, name, "User name is already taken.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	ttl := sessionTTL(cfg)
+	token, err := data.CreateSession(name, ttl)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(cfg, w, token, ttl)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -0,0 +1,74 @@
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestReturnPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", "/"},
+		{"front", "/", "/"},
+		{"page", "/FrontPage", "/FrontPage"},
+		{"page with query", "/FrontPage?a=edit&s=2", "/FrontPage?a=edit&s=2"},
+		{"protocol relative", "//evil.example", "/"},
+		{"protocol relative with path", "//evil.example/FrontPage", "/"},
+		{"backslash", "/\\evil.example", "/"},
+		{"absolute http", "http://evil.example/", "/"},
+		{"absolute https", "https://evil.example/FrontPage", "/"},
+		{"javascript", "javascript:alert(1)", "/"},
+		{"relative", "FrontPage", "/"},
+		{"tab", "/\t/evil.example", "/"},
+		{"newline", "/\n/evil.example", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?a=login&r="+url.QueryEscape(tt.value), nil)
+			if got := returnPath(r); got != tt.want {
+				t.Errorf("returnPath(%q) = %q; want %q", tt.value, got, tt.want)
+			}
+			if got := localPath(tt.value); got != tt.want {
+				t.Errorf("localPath(%q) = %q; want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUserName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"alice", true},
+		{"Alice Smith", true},
+		{"ひめ", true},
+		{"a.b-c_d@example", true},
+		{strings.Repeat("あ", maxUserNameLength), true},
+		{strings.Repeat("あ", maxUserNameLength+1), false},
+		{"", false},
+		{" alice", false},
+		{"alice ", false},
+		{"\talice", false},
+		{"ali\nce", false},
+		{"ali\x00ce", false},
+		{"ali\u0085ce", false},
+		{"ali/ce", false},
+		{"ali\xffce", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validUserName(tt.name); got != tt.want {
+				t.Errorf("validUserName(%q) = %v; want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package action
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+)
+
+func Convert(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid method", http.StatusInternalServerError)
+		return
+	}
+
+	revisionID, content, err := data.Load(params.DbName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if idStr := r.FormValue("revision_id"); idStr != "" {
+		revisionID, err = strconv.Atoi(idStr)
+		if err != nil || revisionID < 0 {
+			http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	converted, err := format.Convert(cfg, params.DbName, content, r.FormValue("to"))
+	if err != nil {
+		http.Error(w, "Failed to convert: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	meta := requestMeta(r, params)
+	meta.Summary = "Converted to " + r.FormValue("to")
+	_, err = data.Save(cfg, params.DbName, converted, PageLinks(cfg, converted), revisionID, meta)
+	if errors.Is(err, data.ErrConflict) {
+		http.Error(w, "Page was changed by others. Reload and convert again.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/"+url.PathEscape(params.Name)+"?b=diff", http.StatusFound)
+}
@@ -0,0 +1,78 @@
+package action
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/config"
+)
+
+const (
+	csrfCookie = "csrf"
+	csrfField  = "csrf"
+)
+
+// csrfToken returns token to embed in forms,
+// setting cookie of new token when the browser has none.
+// Token is checked against the cookie on POST (double submit).
+func csrfToken(cfg *config.Config, w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.Site.Base, "https:"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// sameOrigin reports whether URL of Origin or Referer header
+// points to this site.
+func sameOrigin(cfg *config.Config, r *http.Request, value string) bool {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	base, err := url.Parse(cfg.Site.Base)
+	return err == nil && base.Host != "" && u.Scheme == base.Scheme && u.Host == base.Host
+}
+
+// checkCSRF checks POST request comes from our own form.
+// Form token must match cookie token. Origin or Referer header
+// is checked instead when the browser has no token cookie.
+func checkCSRF(cfg *config.Config, r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "null" {
+		if !sameOrigin(cfg, r, origin) {
+			return false
+		}
+	}
+
+	cookie, err := r.Cookie(csrfCookie)
+	if err == nil && cookie.Value != "" {
+		token := r.FormValue(csrfField)
+		return subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) == 1
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" && origin != "null" {
+		return true
+	}
+	referer := r.Header.Get("Referer")
+	return referer != "" && sameOrigin(cfg, r, referer)
+}
@@ -0,0 +1,87 @@
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+)
+
+func csrfConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Site.Base = "https://wiki.example.org/"
+	return cfg
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"request host", "http://example.com", true},
+		{"request host with path", "http://example.com/FrontPage?a=edit", true},
+		{"site base", "https://wiki.example.org", true},
+		{"site base other scheme", "http://wiki.example.org", false},
+		{"other host", "https://evil.example", false},
+		{"sub domain", "https://wiki.example.org.evil.example", false},
+		{"relative", "/FrontPage", false},
+		{"empty", "", false},
+		{"broken", "http://%zz", false},
+	}
+
+	cfg := csrfConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if got := sameOrigin(cfg, r, tt.value); got != tt.want {
+				t.Errorf("sameOrigin(%q) = %v; want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	tests := []struct {
+		name    string
+		cookie  string
+		form    string
+		origin  string
+		referer string
+		want    bool
+	}{
+		{"token matches", "secret", "csrf=secret", "", "", true},
+		{"token matches same origin", "secret", "csrf=secret", "http://example.com", "", true},
+		{"token differs", "secret", "csrf=other", "", "", false},
+		{"token missing", "secret", "", "", "", false},
+		{"token prefix", "secret", "csrf=secr", "", "", false},
+		{"cross site with valid token", "secret", "csrf=secret", "https://evil.example", "", false},
+		{"null origin falls back to token", "secret", "csrf=secret", "null", "", true},
+		{"no cookie same origin", "", "csrf=whatever", "https://wiki.example.org", "", true},
+		{"no cookie cross site", "", "", "https://evil.example", "", false},
+		{"no cookie same referer", "", "", "", "http://example.com/FrontPage?a=edit", true},
+		{"no cookie cross referer", "", "", "", "https://evil.example/form", false},
+		{"no cookie null origin same referer", "", "", "null", "https://wiki.example.org/FrontPage", true},
+		{"no cookie no headers", "", "csrf=whatever", "", "", false},
+	}
+
+	cfg := csrfConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := postForm(tt.form)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: csrfCookie, Value: tt.cookie})
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				r.Header.Set("Referer", tt.referer)
+			}
+			if got := checkCSRF(cfg, r); got != tt.want {
+				t.Errorf("checkCSRF() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
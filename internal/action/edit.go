@@ -1,6 +1,7 @@
 package action
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
 	"net/url"
@@ -13,6 +14,7 @@ import (
 	"github.com/akikareha/himewiki/internal/data"
 	"github.com/akikareha/himewiki/internal/filter"
 	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/format/ast"
 	"github.com/akikareha/himewiki/internal/templates"
 	"github.com/akikareha/himewiki/internal/util"
 )
@@ -24,51 +26,101 @@ func View(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Pa
 		return
 	}
 
-	title, _, plain, rendered := format.Apply(cfg, params.DbName, content)
+	// stay on redirect page to show diff after editing it
+	query := r.URL.Query()
+	subAction := query.Get("b")
+	redirectError := ""
+	if target, ok := format.Redirect(cfg, content); ok &&
+		query.Get("redirect") != "no" && subAction == "" {
+		final, err := resolveRedirect(cfg, params.DbName, target)
+		if err == nil {
+			http.Redirect(w, r, ast.PagePath(final)+"?from="+url.QueryEscape(params.Name), http.StatusFound)
+			return
+		}
+		redirectError = err.Error()
+	}
+	redirectedFrom := query.Get("from")
+
+	title, _, plain, rendered, outline := format.ApplyEditable(cfg, params.DbName, content)
 	summary := format.TrimForSummary(plain, 144)
 
-	subAction := r.URL.Query().Get("b")
 	diffText := ""
 	if subAction == "diff" {
 		_, prev, _ := data.LoadPrev(params.DbName)
 		diffText = util.Diff(prev, content)
 	}
 
+	backlinks, _ := data.Backlinks(params.DbName)
+
+	// only logout form needs token
+	csrf := ""
+	if params.User != "" {
+		csrf = csrfToken(cfg, w, r)
+	}
+
 	searchName := params.Name
 	if strings.HasSuffix(searchName, ".wiki") {
 		searchName = searchName[:len(searchName)-5]
 	}
 
 	data := struct {
-		Base       string
-		SiteName   string
-		Card       string
-		Name       string
-		Summary    string
-		Title      string
-		SearchName string
-		Rendered   template.HTML
-		Diff       string
+		Base           string
+		SiteName       string
+		Card           string
+		Name           string
+		Summary        string
+		Title          string
+		SearchName     string
+		Rendered       template.HTML
+		Outline        []ast.Section
+		Backlinks      []string
+		Diff           string
+		RedirectedFrom string
+		RedirectError  string
+		User           string
+		CanProtect     bool
+		CSRF           string
 	}{
-		Base:       cfg.Site.Base,
-		SiteName:   cfg.Site.Name,
-		Card:       cfg.Site.Card,
-		Name:       params.Name,
-		Summary:    summary,
-		Title:      title,
-		SearchName: searchName,
-		Rendered:   template.HTML(rendered),
-		Diff:       diffText,
+		Base:           cfg.Site.Base,
+		SiteName:       cfg.Site.Name,
+		Card:           cfg.Site.Card,
+		Name:           params.Name,
+		Summary:        summary,
+		Title:          title,
+		SearchName:     searchName,
+		Rendered:       template.HTML(rendered),
+		Outline:        outline,
+		Backlinks:      backlinks,
+		Diff:           diffText,
+		RedirectedFrom: redirectedFrom,
+		RedirectError:  redirectError,
+		User:           params.User,
+		CanProtect:     params.Role >= data.RoleModerator,
+		CSRF:           csrf,
 	}
 	templates.Render(w, "view", data)
 }
 
+// saveTries limits retries when page changes again while saving.
+const saveTries = 3
+
 func Edit(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if sectionStr := r.URL.Query().Get("s"); sectionStr != "" {
+		section, err := strconv.Atoi(sectionStr)
+		if err != nil || section <= 0 {
+			http.Error(w, "Invalid section", http.StatusBadRequest)
+			return
+		}
+		EditSection(cfg, w, r, params, section)
+		return
+	}
+
 	var previewed bool
 	var revisionID int
 	var content string
 	var preview string
 	var save string
+	meta := formMeta(r, params)
 	if r.Method != http.MethodPost {
 		previewed = false
 		revisionID, content, _ = data.Load(params.DbName)
@@ -99,27 +151,49 @@ func Edit(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Pa
 		http.Error(w, "Failed to filter content", http.StatusInternalServerError)
 		return
 	}
-	title, normalized, _, rendered := format.Apply(cfg, params.DbName, filtered)
+	if filtered != content {
+		meta.Source = data.SourceFilter
+	}
+	// HTML is not shown when saving
+	apply := format.ApplyDisplay
+	if previewed && save != "" {
+		apply = format.Apply
+	}
+	title, normalized, _, rendered, _ := apply(cfg, params.DbName, filtered)
 
 	diffText := ""
+	conflict := false
 	if previewed && save != "" {
-		pageCount, err := data.Save(cfg, params.DbName, normalized, revisionID)
+		baseRevID := revisionID
+		pageCount, err := data.Save(cfg, params.DbName, normalized, PageLinks(cfg, normalized), revisionID, meta)
+		// others may save again between merge and save, so merge again
+		for tries := 1; errors.Is(err, data.ErrConflict); tries++ {
+			var merged, current string
+			revisionID, current, merged, conflict, err = mergeEdit(cfg, params.DbName, baseRevID, normalized)
+			if err != nil {
+				break
+			}
+			if conflict || tries >= saveTries {
+				// keep user's text with conflict markers
+				conflict = true
+				previewed = false
+				title, normalized, _, rendered, _ = format.Apply(cfg, params.DbName, merged)
+				diffText = util.Diff(current, normalized)
+				break
+			}
+			pageCount, err = data.Save(cfg, params.DbName, merged, PageLinks(cfg, merged), revisionID, meta)
+		}
 		if err != nil {
 			http.Error(w, "Failed to save", http.StatusInternalServerError)
 			return
 		}
 
-		if cfg.Gnome.Agent != "nil" {
-			if pageCount%int64(cfg.Gnome.Ratio) == 0 {
-				targetRecent := (pageCount / int64(cfg.Gnome.Ratio)) % int64(cfg.Gnome.Recent)
-				go func() {
-					runGnome(cfg, int(targetRecent))
-				}()
-			}
-		}
+		if !conflict {
+			scheduleGnome(cfg, pageCount)
 
-		http.Redirect(w, r, "/"+url.PathEscape(params.Name)+"?b=diff", http.StatusFound)
-		return
+			http.Redirect(w, r, "/"+url.PathEscape(params.Name)+"?b=diff", http.StatusFound)
+			return
+		}
 	} else if preview != "" {
 		previewed = true
 		_, current, _ := data.Load(params.DbName)
@@ -138,22 +212,32 @@ func Edit(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Pa
 		SiteName   string
 		Name       string
 		Previewed  bool
+		Conflict   bool
 		RevisionID int
+		Section    int
+		BaseHash   string
 		Text       string
+		Summary    string
+		Minor      bool
 		Title      string
 		SearchName string
 		Rendered   template.HTML
 		Diff       string
+		CSRF       string
 	}{
 		SiteName:   cfg.Site.Name,
 		Name:       params.Name,
 		Previewed:  previewed,
+		Conflict:   conflict,
 		RevisionID: revisionID,
 		Text:       normalized,
+		Summary:    meta.Summary,
+		Minor:      meta.Minor,
 		Title:      title,
 		SearchName: searchName,
 		Rendered:   template.HTML(rendered),
 		Diff:       diffText,
+		CSRF:       csrfToken(cfg, w, r),
 	}
 	templates.Render(w, "edit", data)
 }
@@ -194,20 +278,27 @@ func Recent(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *
 		page = 1
 	}
 
-	records, err := data.Recent(page, perPage)
+	hideMinor := r.URL.Query().Get("hideminor") == "true"
+	hideBots := r.URL.Query().Get("hidebots") == "true"
+
+	records, err := data.Recent(page, perPage, hideMinor, hideBots)
 	if err != nil {
 		http.Error(w, "Failed to load pages", http.StatusInternalServerError)
 		return
 	}
 
 	data := struct {
-		SiteName string
-		Records  []data.RecentRecord
-		NextPage int
+		SiteName  string
+		Records   []data.RecentRecord
+		HideMinor bool
+		HideBots  bool
+		NextPage  int
 	}{
-		SiteName: cfg.Site.Name,
-		Records:  records,
-		NextPage: page + 1,
+		SiteName:  cfg.Site.Name,
+		Records:   records,
+		HideMinor: hideMinor,
+		HideBots:  hideBots,
+		NextPage:  page + 1,
 	}
 	templates.Render(w, "recent", data)
 }
@@ -1,12 +1,31 @@
 package action
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/akikareha/himewiki/internal/config"
 	"github.com/akikareha/himewiki/internal/data"
 	"github.com/akikareha/himewiki/internal/filter"
 	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/templates"
+	"github.com/akikareha/himewiki/internal/util"
 )
 
+// scheduleGnome runs gnome in background every configured page saves.
+func scheduleGnome(cfg *config.Config, pageCount int64) {
+	if cfg.Gnome.Agent == "nil" {
+		return
+	}
+	if pageCount%int64(cfg.Gnome.Ratio) != 0 {
+		return
+	}
+	targetRecent := (pageCount / int64(cfg.Gnome.Ratio)) % int64(cfg.Gnome.Recent)
+	go func() {
+		runGnome(cfg, int(targetRecent))
+	}()
+}
+
 func runGnome(cfg *config.Config, targetRecent int) {
 	recentNames, err := data.RecentNames(cfg.Gnome.Recent)
 	if err != nil {
@@ -23,10 +42,149 @@ func runGnome(cfg *config.Config, targetRecent int) {
 	}
 
 	filtered, err := filter.GnomeApply(cfg, targetName, content)
-	_, normalized, _, _ := format.Apply(cfg, targetName, filtered)
+	_, normalized, _, _, _ := format.Apply(cfg, targetName, filtered)
 
-	_, err = data.Save(cfg, targetName, normalized, revisionID)
+	_, err = data.Save(cfg, targetName, normalized, PageLinks(cfg, normalized), revisionID, data.Meta{
+		Author:  cfg.Gnome.Agent,
+		Summary: "Gardening by gnome",
+		Source:  data.SourceGnome,
+	})
 	if err != nil {
 		return
 	}
 }
+
+// revertGnome reverts gnome edits made in time window.
+// Each edit is undone by merging its reverse change,
+// so later edits by humans are kept.
+// Pages which could not be reverted cleanly are left as they are,
+// and so are pages protected above role.
+func revertGnome(cfg *config.Config, from string, to string, role data.Role, meta data.Meta) (
+	[]string, []string, []string, error,
+) {
+	revs, err := data.RevisionsBySource(data.SourceGnome, from, to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var names []string
+	byName := map[string][]data.Revision{}
+	for _, rev := range revs {
+		if _, ok := byName[rev.Name]; !ok {
+			names = append(names, rev.Name)
+		}
+		byName[rev.Name] = append(byName[rev.Name], rev)
+	}
+
+	var reverted, failed, skipped []string
+	for _, name := range names {
+		protection, err := data.Protection(name)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		if protection > role {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		revisionID, current, err := data.Load(name)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+
+		text := current
+		clean := true
+		for _, rev := range byName[name] {
+			prev, err := data.LoadPrevRevision(name, rev.ID)
+			if err != nil {
+				clean = false
+				break
+			}
+			text, clean = util.Merge3(rev.Content, text, prev)
+			if !clean {
+				break
+			}
+		}
+		if !clean {
+			failed = append(failed, name)
+			continue
+		}
+		if text == current {
+			continue
+		}
+
+		_, normalized, _, _, _ := format.Apply(cfg, name, text)
+		_, err = data.Save(cfg, name, normalized, PageLinks(cfg, normalized), revisionID, meta)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		reverted = append(reverted, name)
+	}
+	return reverted, failed, skipped, nil
+}
+
+// parseWindowTime parses time of datetime-local input.
+func parseWindowTime(value string) (string, error) {
+	t, err := time.Parse("2006-01-02T15:04", value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format("2006-01-02 15:04"), nil
+}
+
+// GnomeRevert reverts all gnome edits made in time window,
+// e.g. after bad prompt damaged many pages.
+func GnomeRevert(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	var reverted, failed, skipped []string
+	done := false
+	fromStr := r.FormValue("from")
+	toStr := r.FormValue("to")
+	if r.Method == http.MethodPost {
+		from, err := parseWindowTime(fromStr)
+		if err != nil {
+			http.Error(w, "Invalid start time", http.StatusBadRequest)
+			return
+		}
+		to, err := parseWindowTime(toStr)
+		if err != nil {
+			http.Error(w, "Invalid end time", http.StatusBadRequest)
+			return
+		}
+
+		meta := requestMeta(r, params)
+		meta.Summary = "Reverted gnome edits from " + from + " to " + to
+		reverted, failed, skipped, err = revertGnome(cfg, from, to, params.Role, meta)
+		if err != nil {
+			http.Error(w, "Failed to revert", http.StatusInternalServerError)
+			return
+		}
+		done = true
+	}
+
+	data := struct {
+		SiteName string
+		From     string
+		To       string
+		Done     bool
+		Reverted []string
+		Failed   []string
+		Skipped  []string
+		CSRF     string
+	}{
+		SiteName: cfg.Site.Name,
+		From:     fromStr,
+		To:       toStr,
+		Done:     done,
+		Reverted: reverted,
+		Failed:   failed,
+		Skipped:  skipped,
+		CSRF:     csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "gnomerevert", data)
+}
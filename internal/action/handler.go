@@ -9,6 +9,7 @@ import (
 	"golang.org/x/text/unicode/norm"
 
 	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
 )
 
 type Params struct {
@@ -17,6 +18,15 @@ type Params struct {
 	Ext    string
 	Action string
 	ID     *int
+
+	// User is name of signed in user, or empty for anonymous.
+	User string
+
+	// Role is role of user.
+	Role data.Role
+
+	// Addr is address of client.
+	Addr string
 }
 
 func parse(cfg *config.Config, r *http.Request) Params {
@@ -58,8 +68,22 @@ func parse(cfg *config.Config, r *http.Request) Params {
 	}
 }
 
-func handle(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
+func handle(cfg *config.Config, lim *limits, w http.ResponseWriter, r *http.Request) {
 	params := parse(cfg, r)
+	params.User = sessionUser(r)
+	params.Addr = clientAddr(cfg, r)
+	if !lim.allow(w, r, &params) {
+		return
+	}
+	params.Role = userRole(cfg, params.User)
+	if r.Method == http.MethodPost && !checkCSRF(cfg, r) {
+		forbidden(cfg, w, &params,
+			"This request did not come from a form of this site. Please reload the form and try again.")
+		return
+	}
+	if !authorize(cfg, w, r, &params) {
+		return
+	}
 
 	if params.Ext == "wiki" {
 		switch params.Action {
@@ -69,18 +93,60 @@ func handle(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
 			View(cfg, w, r, &params)
 		case "edit":
 			Edit(cfg, w, r, &params)
+		case "move":
+			Move(cfg, w, r, &params)
+		case "delete":
+			Delete(cfg, w, r, &params)
+		case "trash":
+			Trash(cfg, w, r, &params)
+		case "restore":
+			Restore(cfg, w, r, &params)
+		case "purge":
+			Purge(cfg, w, r, &params)
+		case "protect":
+			Protect(cfg, w, r, &params)
+		case "users":
+			Users(cfg, w, r, &params)
+		case "convert":
+			Convert(cfg, w, r, &params)
 		case "all":
 			All(cfg, w, r, &params)
 		case "recent":
 			Recent(cfg, w, r, &params)
+		case "backlinks":
+			Backlinks(cfg, w, r, &params)
+		case "orphans":
+			Orphans(cfg, w, r, &params)
+		case "wanted":
+			Wanted(cfg, w, r, &params)
 		case "revs":
 			Revisions(cfg, w, r, &params)
 		case "revert":
 			Revert(cfg, w, r, &params)
+		case "blame":
+			Blame(cfg, w, r, &params)
+		case "compare":
+			Compare(cfg, w, r, &params)
 		case "rev":
 			ViewRevision(cfg, w, r, &params)
+		case "login":
+			Login(cfg, w, r, &params)
+		case "logout":
+			Logout(cfg, w, r, &params)
+		case "signup":
+			Signup(cfg, w, r, &params)
+		case "sso":
+			SSO(cfg, w, r, &params)
+		case "oidccallback":
+			SSOCallback(cfg, w, r, &params)
 		case "search":
 			Search(cfg, w, r, &params)
+		case "suggest":
+			Suggest(cfg, w, r, &params)
+		case "opensearch":
+			OpenSearch(cfg, w, r, &params)
+		case "gnomerevert":
+			GnomeRevert(cfg, w, r, &params)
 		case "upload":
 			Upload(cfg, w, r, &params)
 		case "allimgs":
@@ -99,10 +165,11 @@ func handle(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
 }
 
 func Handler(cfg *config.Config) http.HandlerFunc {
+	lim := newLimits(cfg)
 	return func(w http.ResponseWriter, r *http.Request) {
 		if handleStatic(cfg, w, r) {
 			return
 		}
-		handle(cfg, w, r)
+		handle(cfg, lim, w, r)
 	}
 }
@@ -92,10 +92,12 @@ func Upload(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *
 		Base     string
 		SiteName string
 		Name     string
+		CSRF     string
 	}{
 		Base:     cfg.Site.Base,
 		SiteName: cfg.Site.Name,
 		Name:     name,
+		CSRF:     csrfToken(cfg, w, r),
 	}
 	templates.Render(w, "upload", data)
 }
@@ -0,0 +1,91 @@
+package action
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/templates"
+)
+
+// PageLinks lists outgoing links of content to store with page.
+func PageLinks(cfg *config.Config, content string) []data.Link {
+	var links []data.Link
+	for _, link := range format.Links(cfg, content) {
+		links = append(links, data.Link{Target: link.Name, Inter: link.Inter})
+	}
+	return links
+}
+
+func Backlinks(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	backlinks, err := data.Backlinks(params.DbName)
+	if err != nil {
+		http.Error(w, "Failed to load backlinks", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		SiteName  string
+		Name      string
+		Title     string
+		Backlinks []string
+	}{
+		SiteName:  cfg.Site.Name,
+		Name:      params.Name,
+		Title:     params.DbName,
+		Backlinks: backlinks,
+	}
+	templates.Render(w, "backlinks", data)
+}
+
+func Orphans(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	pageStr := r.URL.Query().Get("p")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+
+	pages, err := data.Orphans(page, perBigPage)
+	if err != nil {
+		http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		SiteName string
+		Pages    []string
+		NextPage int
+	}{
+		SiteName: cfg.Site.Name,
+		Pages:    pages,
+		NextPage: page + 1,
+	}
+	templates.Render(w, "orphans", data)
+}
+
+func Wanted(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	pageStr := r.URL.Query().Get("p")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+
+	records, err := data.Wanted(page, perBigPage)
+	if err != nil {
+		http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		SiteName string
+		Records  []data.WantedRecord
+		NextPage int
+	}{
+		SiteName: cfg.Site.Name,
+		Records:  records,
+		NextPage: page + 1,
+	}
+	templates.Render(w, "wanted", data)
+}
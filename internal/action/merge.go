@@ -0,0 +1,35 @@
+package action
+
+import (
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/util"
+)
+
+// mergeEdit merges text edited on base revision into current revision.
+// It returns current revision id and text, merged text
+// and whether the merge has conflicts.
+func mergeEdit(cfg *config.Config, name string, baseRevID int, text string) (
+	int, string, string, bool, error,
+) {
+	base := ""
+	if baseRevID > 0 {
+		var err error
+		base, err = data.LoadRevision(name, baseRevID)
+		if err != nil {
+			return 0, "", "", false, err
+		}
+	}
+
+	currentRevID, current, err := data.Load(name)
+	if err != nil {
+		return 0, "", "", false, err
+	}
+
+	merged, clean := util.Merge3(base, current, text)
+	if clean {
+		_, merged, _, _, _ = format.Apply(cfg, name, merged)
+	}
+	return currentRevID, current, merged, !clean, nil
+}
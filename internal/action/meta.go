@@ -0,0 +1,54 @@
+package action
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+)
+
+const summaryLength = 200
+
+// clientAddr returns address of client without port.
+// When site is behind reverse proxy, address is taken from
+// header set by proxy, which must overwrite one sent by client.
+// For X-Forwarded-For, the last address, added by proxy, is taken.
+func clientAddr(cfg *config.Config, r *http.Request) string {
+	if header := cfg.App.ProxyHeader; header != "" {
+		values := strings.Split(r.Header.Get(header), ",")
+		if addr := strings.TrimSpace(values[len(values)-1]); addr != "" {
+			return addr
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestMeta makes revision metadata of edit by requesting user.
+// Author is user name when signed in, or client address.
+func requestMeta(r *http.Request, params *Params) data.Meta {
+	author := params.User
+	if author == "" {
+		author = params.Addr
+	}
+	return data.Meta{
+		Author: author,
+		Source: data.SourceHuman,
+	}
+}
+
+// formMeta is same as requestMeta
+// but also takes edit summary and minor flag from form.
+func formMeta(r *http.Request, params *Params) data.Meta {
+	meta := requestMeta(r, params)
+	meta.Summary = format.TrimForSummary(r.FormValue("summary"), summaryLength)
+	meta.Minor = r.FormValue("minor") == "true"
+	return meta
+}
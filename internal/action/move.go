@@ -0,0 +1,165 @@
+package action
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/format/ast"
+	"github.com/akikareha/himewiki/internal/templates"
+)
+
+// renameLinks rewrites links to page from in page name
+// and saves it as new revision.
+func renameLinks(cfg *config.Config, name string, from string, to string, meta data.Meta) error {
+	revisionID, content, err := data.Load(name)
+	if err != nil {
+		return err
+	}
+	renamed, changed := format.RenameLinks(cfg, content, from, to)
+	if !changed {
+		return nil
+	}
+	_, err = data.Save(cfg, name, renamed, PageLinks(cfg, renamed), revisionID, meta)
+	return err
+}
+
+// moveError returns message and status code for error of moving page.
+func moveError(err error) (string, int) {
+	if errors.Is(err, data.ErrExists) {
+		return "Page already exists", http.StatusConflict
+	} else if errors.Is(err, data.ErrTrashed) {
+		return "Page of this name is in trash. Restore or purge it first.", http.StatusConflict
+	} else if errors.Is(err, pgx.ErrNoRows) {
+		return "Page not found", http.StatusNotFound
+	}
+	return "Failed to move", http.StatusInternalServerError
+}
+
+// Move renames page with its history.
+// It optionally leaves redirect page at old name
+// and rewrites links to old name in other pages.
+func Move(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if _, _, err := data.Load(params.DbName); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		data := struct {
+			SiteName  string
+			Name      string
+			Title     string
+			Moved     string
+			MovedPath string
+			Skipped   []string
+			CSRF      string
+		}{
+			SiteName: cfg.Site.Name,
+			Name:     params.Name,
+			Title:    params.DbName,
+			CSRF:     csrfToken(cfg, w, r),
+		}
+		templates.Render(w, "move", data)
+		return
+	}
+
+	to := norm.NFC.String(strings.TrimSpace(r.FormValue("to")))
+	to = strings.TrimSuffix(to, ".wiki")
+	if to == "" || to[0] == '.' || strings.ContainsAny(to, "/?#") {
+		http.Error(w, "Invalid page name", http.StatusBadRequest)
+		return
+	}
+	if to == params.DbName {
+		http.Error(w, "Same page name", http.StatusBadRequest)
+		return
+	}
+
+	// new name may be protected against creation
+	allowed, err := canChange(params, to)
+	if err != nil {
+		http.Error(w, "Failed to load protection", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		forbidden(cfg, w, params, "Page "+to+" is protected.")
+		return
+	}
+
+	var stub string
+	if r.FormValue("redirect") == "true" {
+		_, stub, _, _, _ = format.Apply(cfg, params.DbName, "<<redirect "+to+">>\n")
+	}
+
+	var sources, skipped []string
+	if r.FormValue("fixlinks") == "true" {
+		backlinks, err := data.Backlinks(params.DbName)
+		if err != nil {
+			http.Error(w, "Failed to load backlinks", http.StatusInternalServerError)
+			return
+		}
+		for _, source := range backlinks {
+			allowed, err := canChange(params, source)
+			if err != nil {
+				http.Error(w, "Failed to load protection", http.StatusInternalServerError)
+				return
+			}
+			if allowed {
+				sources = append(sources, source)
+			} else {
+				skipped = append(skipped, source)
+			}
+		}
+		// moved page may link to itself by old name
+		sources = append(sources, to)
+	}
+
+	meta := requestMeta(r, params)
+	meta.Summary = "Moved " + params.DbName + " to " + to
+	err = data.Move(params.DbName, to, stub, PageLinks(cfg, stub), meta)
+	if err != nil {
+		message, code := moveError(err)
+		http.Error(w, message, code)
+		return
+	}
+
+	for _, source := range sources {
+		err := renameLinks(cfg, source, params.DbName, to, meta)
+		if err != nil {
+			log.Printf("failed to rename links in %s: %v", source, err)
+		}
+	}
+
+	if len(skipped) > 0 {
+		data := struct {
+			SiteName  string
+			Name      string
+			Title     string
+			Moved     string
+			MovedPath string
+			Skipped   []string
+			CSRF      string
+		}{
+			SiteName:  cfg.Site.Name,
+			Name:      params.Name,
+			Title:     params.DbName,
+			Moved:     to,
+			MovedPath: ast.PagePath(to),
+			Skipped:   skipped,
+		}
+		templates.Render(w, "move", data)
+		return
+	}
+
+	http.Redirect(w, r, ast.PagePath(to), http.StatusFound)
+}
@@ -0,0 +1,45 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/akikareha/himewiki/internal/data"
+)
+
+func TestMoveError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"exists", data.ErrExists, http.StatusConflict},
+		{"trashed", data.ErrTrashed, http.StatusConflict},
+		{"wrapped trashed", fmt.Errorf("move: %w", data.ErrTrashed), http.StatusConflict},
+		{"no page", pgx.ErrNoRows, http.StatusNotFound},
+		{"other", errors.New("broken"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, code := moveError(tt.err)
+			if code != tt.wantCode {
+				t.Errorf("moveError(%v) code = %d; want %d", tt.err, code, tt.wantCode)
+			}
+			if message == "" {
+				t.Errorf("moveError(%v) message is empty", tt.err)
+			}
+		})
+	}
+
+	// trashed name is told apart from existing page
+	exists, _ := moveError(data.ErrExists)
+	trashed, _ := moveError(data.ErrTrashed)
+	if exists == trashed {
+		t.Errorf("moveError() messages of existing and trashed page are same: %q", exists)
+	}
+}
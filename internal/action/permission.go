@@ -0,0 +1,259 @@
+package action
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format/ast"
+	"github.com/akikareha/himewiki/internal/templates"
+)
+
+// actionRoles are roles needed for actions changing the wiki.
+// Other actions are open to readers.
+var actionRoles = map[string]data.Role{
+	"edit":        data.RoleEditor,
+	"move":        data.RoleEditor,
+	"delete":      data.RoleEditor,
+	"convert":     data.RoleEditor,
+	"revert":      data.RoleEditor,
+	"upload":      data.RoleEditor,
+	"restore":     data.RoleModerator,
+	"protect":     data.RoleModerator,
+	"gnomerevert": data.RoleModerator,
+	"purge":       data.RoleAdmin,
+	"users":       data.RoleAdmin,
+}
+
+// pageActions are actions changing the page,
+// which also need role of protection of the page.
+var pageActions = map[string]bool{
+	"edit":    true,
+	"move":    true,
+	"delete":  true,
+	"convert": true,
+	"revert":  true,
+	"restore": true,
+	"protect": true,
+}
+
+// pageProtection loads role needed to change the page.
+// Tests replace it to run without database.
+var pageProtection = data.Protection
+
+// userRole returns role of signed in user.
+// Anonymous users are editors unless sign in is required.
+func userRole(cfg *config.Config, user string) data.Role {
+	if user == "" {
+		if cfg.Auth.RequireLogin {
+			return data.RoleReader
+		}
+		return data.RoleEditor
+	}
+
+	role, err := data.UserRole(user)
+	if err != nil {
+		if !errors.Is(err, data.ErrNoUser) {
+			log.Println("failed to load role:", err)
+		}
+		return data.RoleReader
+	}
+	return role
+}
+
+// authorize checks user may do the action.
+// Anonymous users are sent to login page and others get forbidden page
+// when not allowed. It returns true when allowed.
+func authorize(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) bool {
+	need, ok := actionRoles[params.Action]
+	if !ok {
+		return true
+	}
+	if pageActions[params.Action] && params.Ext == "wiki" {
+		protection, err := pageProtection(params.DbName)
+		if err != nil {
+			http.Error(w, "Failed to load protection", http.StatusInternalServerError)
+			return false
+		}
+		if protection > need {
+			need = protection
+		}
+	}
+	if params.Role >= need {
+		return true
+	}
+
+	if params.User == "" {
+		http.Redirect(w, r, "/?a=login&r="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return false
+	}
+	forbidden(cfg, w, params,
+		"Action \""+params.Action+"\" on this page needs role "+need.String()+".")
+	return false
+}
+
+// canChange reports whether user may change the page
+// under its protection.
+func canChange(params *Params, name string) (bool, error) {
+	protection, err := pageProtection(name)
+	if err != nil {
+		return false, err
+	}
+	return params.Role >= protection, nil
+}
+
+// forbidden renders forbidden page with status 403.
+func forbidden(cfg *config.Config, w http.ResponseWriter, params *Params, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusForbidden)
+
+	data := struct {
+		SiteName string
+		Name     string
+		Message  string
+		User     string
+		Role     string
+	}{
+		SiteName: cfg.Site.Name,
+		Name:     params.Name,
+		Message:  message,
+		User:     params.User,
+		Role:     params.Role.String(),
+	}
+	templates.Render(w, "forbidden", data)
+}
+
+// Protect sets role needed to change the page.
+func Protect(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		protection, err := data.Protection(params.DbName)
+		if err != nil {
+			http.Error(w, "Failed to load protection", http.StatusInternalServerError)
+			return
+		}
+
+		var roles []string
+		for _, role := range data.Roles {
+			if role >= data.RoleEditor && role <= params.Role {
+				roles = append(roles, role.String())
+			}
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		data := struct {
+			SiteName   string
+			Name       string
+			Title      string
+			Protection string
+			Roles      []string
+			CSRF       string
+		}{
+			SiteName:   cfg.Site.Name,
+			Name:       params.Name,
+			Title:      params.DbName,
+			Protection: protection.String(),
+			Roles:      roles,
+			CSRF:       csrfToken(cfg, w, r),
+		}
+		templates.Render(w, "protect", data)
+		return
+	}
+
+	role, ok := data.ParseRole(r.FormValue("role"))
+	if !ok || role > params.Role {
+		http.Error(w, "Bad role", http.StatusBadRequest)
+		return
+	}
+
+	err := data.SetProtection(params.DbName, role)
+	if err != nil {
+		http.Error(w, "Failed to protect", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, ast.PagePath(params.DbName), http.StatusFound)
+}
+
+// Users lists users and changes their roles.
+func Users(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method == http.MethodPost {
+		name := r.FormValue("name")
+		role, ok := data.ParseRole(r.FormValue("role"))
+		if !ok {
+			http.Error(w, "Bad role", http.StatusBadRequest)
+			return
+		}
+		if name == params.User {
+			http.Error(w, "Cannot change own role", http.StatusBadRequest)
+			return
+		}
+
+		err := data.SetUserRole(name, role)
+		if errors.Is(err, data.ErrNoUser) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to change role", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/?a=users", http.StatusFound)
+		return
+	}
+
+	pageStr := r.URL.Query().Get("p")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+
+	records, err := data.Users(page, perBigPage)
+	if err != nil {
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
+	type userRow struct {
+		Name string
+		Role string
+		Self bool
+	}
+	rows := make([]userRow, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, userRow{
+			Name: record.Name,
+			Role: record.Role.String(),
+			Self: record.Name == params.User,
+		})
+	}
+	var roles []string
+	for _, role := range data.Roles {
+		roles = append(roles, role.String())
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	data := struct {
+		SiteName string
+		Users    []userRow
+		Roles    []string
+		NextPage int
+		CSRF     string
+	}{
+		SiteName: cfg.Site.Name,
+		Users:    rows,
+		Roles:    roles,
+		NextPage: page + 1,
+		CSRF:     csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "users", data)
+}
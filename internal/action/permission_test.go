@@ -0,0 +1,166 @@
+package action
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+)
+
+// mockProtection protects "Locked" for moderators
+// and "Closed" for admins.
+func mockProtection(name string) (data.Role, error) {
+	switch name {
+	case "Locked":
+		return data.RoleModerator, nil
+	case "Closed":
+		return data.RoleAdmin, nil
+	case "Broken":
+		return data.RoleEditor, errors.New("broken")
+	}
+	return data.RoleEditor, nil
+}
+
+func useMockProtection(t *testing.T) {
+	saved := pageProtection
+	pageProtection = mockProtection
+	t.Cleanup(func() { pageProtection = saved })
+}
+
+func TestAuthorizeMatrix(t *testing.T) {
+	useMockProtection(t)
+
+	actions := []string{"", "view", "search", "history"}
+	for action := range actionRoles {
+		actions = append(actions, action)
+	}
+
+	cfg := &config.Config{}
+	for _, role := range data.Roles {
+		for _, action := range actions {
+			name := role.String() + "/" + action
+			t.Run(name, func(t *testing.T) {
+				params := &Params{
+					Name:   "FrontPage",
+					DbName: "FrontPage",
+					Ext:    "wiki",
+					Action: action,
+					User:   "alice",
+					Role:   role,
+				}
+				want := role >= actionRoles[action]
+
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/FrontPage?a="+action, nil)
+				got := authorize(cfg, w, r, params)
+				if got != want {
+					t.Fatalf("authorize() = %v; want %v", got, want)
+				}
+				if !got && w.Code != http.StatusForbidden {
+					t.Errorf("status = %d; want %d", w.Code, http.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestAuthorizeProtection(t *testing.T) {
+	useMockProtection(t)
+
+	tests := []struct {
+		name     string
+		page     string
+		ext      string
+		action   string
+		user     string
+		role     data.Role
+		want     bool
+		wantCode int
+	}{
+		{"editor edits open page", "FrontPage", "wiki", "edit", "alice", data.RoleEditor, true, http.StatusOK},
+		{"editor edits locked page", "Locked", "wiki", "edit", "alice", data.RoleEditor, false, http.StatusForbidden},
+		{"moderator edits locked page", "Locked", "wiki", "edit", "alice", data.RoleModerator, true, http.StatusOK},
+		{"moderator moves closed page", "Closed", "wiki", "move", "alice", data.RoleModerator, false, http.StatusForbidden},
+		{"admin deletes closed page", "Closed", "wiki", "delete", "alice", data.RoleAdmin, true, http.StatusOK},
+		{"moderator protects closed page", "Closed", "wiki", "protect", "alice", data.RoleModerator, false, http.StatusForbidden},
+		{"reader views locked page", "Locked", "wiki", "view", "alice", data.RoleReader, true, http.StatusOK},
+		{"editor sees history of locked page", "Locked", "wiki", "history", "alice", data.RoleEditor, true, http.StatusOK},
+		{"protection only on wiki pages", "Locked", "png", "upload", "alice", data.RoleEditor, true, http.StatusOK},
+		{"anonymous sent to login", "Locked", "wiki", "edit", "", data.RoleEditor, false, http.StatusFound},
+		{"failed lookup", "Broken", "wiki", "edit", "alice", data.RoleAdmin, false, http.StatusInternalServerError},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &Params{
+				Name:   tt.page,
+				DbName: tt.page,
+				Ext:    tt.ext,
+				Action: tt.action,
+				User:   tt.user,
+				Role:   tt.role,
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.page+"?a="+tt.action, nil)
+			if got := authorize(cfg, w, r, params); got != tt.want {
+				t.Fatalf("authorize() = %v; want %v", got, tt.want)
+			}
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusFound {
+				location := w.Header().Get("Location")
+				if !strings.HasPrefix(location, "/?a=login&r=") {
+					t.Errorf("Location = %q; want login page", location)
+				}
+			}
+		})
+	}
+}
+
+func TestCanChange(t *testing.T) {
+	useMockProtection(t)
+
+	tests := []struct {
+		page    string
+		role    data.Role
+		want    bool
+		wantErr bool
+	}{
+		{"FrontPage", data.RoleEditor, true, false},
+		{"FrontPage", data.RoleReader, false, false},
+		{"Locked", data.RoleEditor, false, false},
+		{"Locked", data.RoleModerator, true, false},
+		{"Closed", data.RoleModerator, false, false},
+		{"Closed", data.RoleAdmin, true, false},
+		{"Broken", data.RoleAdmin, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page+"/"+tt.role.String(), func(t *testing.T) {
+			got, err := canChange(&Params{Role: tt.role}, tt.page)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("canChange() error = %v; want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("canChange() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRoleAnonymous(t *testing.T) {
+	cfg := &config.Config{}
+	if got := userRole(cfg, ""); got != data.RoleEditor {
+		t.Errorf("userRole() = %v; want %v", got, data.RoleEditor)
+	}
+	cfg.Auth.RequireLogin = true
+	if got := userRole(cfg, ""); got != data.RoleReader {
+		t.Errorf("userRole() with login required = %v; want %v", got, data.RoleReader)
+	}
+}
@@ -0,0 +1,96 @@
+package action
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/ratelimit"
+)
+
+// limits are rate limiters of each kind of request.
+type limits struct {
+	view   *ratelimit.Limiter
+	search *ratelimit.Limiter
+	edit   *ratelimit.Limiter
+	upload *ratelimit.Limiter
+	ai     *ratelimit.Limiter
+}
+
+func newLimits(cfg *config.Config) *limits {
+	rl := cfg.RateLimit
+	return &limits{
+		view:   ratelimit.New(rl.View.PerMinute, rl.View.Burst),
+		search: ratelimit.New(rl.Search.PerMinute, rl.Search.Burst),
+		edit:   ratelimit.New(rl.Edit.PerMinute, rl.Edit.Burst),
+		upload: ratelimit.New(rl.Upload.PerMinute, rl.Upload.Burst),
+		ai:     ratelimit.New(rl.AI.PerMinute, rl.AI.Burst),
+	}
+}
+
+// callsFilter reports whether request runs AI filter,
+// i.e. saving edit after preview or uploading image.
+func callsFilter(r *http.Request, params *Params) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch params.Action {
+	case "edit":
+		return r.FormValue("previewed") == "true" && r.FormValue("save") != ""
+	case "upload":
+		return true
+	}
+	return false
+}
+
+// limiters returns limiters request counts against.
+func (l *limits) limiters(r *http.Request, params *Params) []*ratelimit.Limiter {
+	var ls []*ratelimit.Limiter
+	if r.Method == http.MethodPost {
+		if params.Action == "upload" {
+			ls = append(ls, l.upload)
+		} else {
+			ls = append(ls, l.edit)
+		}
+		if callsFilter(r, params) {
+			ls = append(ls, l.ai)
+		}
+		return ls
+	}
+
+	switch params.Action {
+	case "search", "suggest":
+		return append(ls, l.search)
+	}
+	return append(ls, l.view)
+}
+
+// allow checks rate limits of client, which is user if signed in
+// or client address. When limited, it responds 429 with Retry-After
+// and returns false.
+func (l *limits) allow(w http.ResponseWriter, r *http.Request, params *Params) bool {
+	key := "ip:" + params.Addr
+	if params.User != "" {
+		key = "user:" + params.User
+	}
+
+	// rejected request must not use up other limits
+	var taken []*ratelimit.Limiter
+	for _, limiter := range l.limiters(r, params) {
+		ok, wait := limiter.Allow(key)
+		if ok {
+			taken = append(taken, limiter)
+			continue
+		}
+		for _, t := range taken {
+			t.Refund(key)
+		}
+
+		seconds := int((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
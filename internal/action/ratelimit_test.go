@@ -0,0 +1,77 @@
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/ratelimit"
+)
+
+func postForm(form string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/FrontPage?a=edit", strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestAllowRefunds(t *testing.T) {
+	lim := &limits{
+		edit: ratelimit.New(1, 2),
+		ai:   ratelimit.New(1, 1),
+	}
+	params := &Params{Action: "edit", Addr: "192.0.2.1"}
+	save := "previewed=true&save=Save"
+
+	if w := httptest.NewRecorder(); !lim.allow(w, postForm(save), params) {
+		t.Fatal("first save denied")
+	}
+	w := httptest.NewRecorder()
+	if lim.allow(w, postForm(save), params) {
+		t.Fatal("second save allowed over AI limit")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("got %d with Retry-After %q; want 429 with Retry-After",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+	// edit token taken by rejected save is given back
+	if w := httptest.NewRecorder(); !lim.allow(w, postForm("preview=Preview"), params) {
+		t.Error("preview denied after rejected save")
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		remote string
+		values map[string]string
+		want   string
+	}{
+		{"remote", "", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"no port", "", "192.0.2.1", nil, "192.0.2.1"},
+		{"header ignored", "", "192.0.2.1:1234",
+			map[string]string{"X-Real-IP": "198.51.100.1"}, "192.0.2.1"},
+		{"real ip", "X-Real-IP", "127.0.0.1:1234",
+			map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"forwarded last", "X-Forwarded-For", "127.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "203.0.113.9, 198.51.100.1"}, "198.51.100.1"},
+		{"header missing", "X-Real-IP", "127.0.0.1:1234", nil, "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.App.ProxyHeader = tt.header
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.values {
+				r.Header.Set(k, v)
+			}
+			if got := clientAddr(cfg, r); got != tt.want {
+				t.Errorf("clientAddr() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package action
+
+import (
+	"errors"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+)
+
+const maxRedirects = 8
+
+var (
+	errRedirectLoop    = errors.New("redirect loop")
+	errRedirectMissing = errors.New("redirect target does not exist")
+)
+
+// resolveRedirect follows redirects from page name to target
+// and returns final page which is not redirect.
+func resolveRedirect(cfg *config.Config, name string, target string) (string, error) {
+	visited := map[string]bool{name: true}
+	for i := 0; i < maxRedirects; i++ {
+		if visited[target] {
+			return "", errRedirectLoop
+		}
+		visited[target] = true
+
+		_, content, err := data.Load(target)
+		if err != nil {
+			return "", errRedirectMissing
+		}
+		next, ok := format.Redirect(cfg, content)
+		if !ok {
+			return target, nil
+		}
+		target = next
+	}
+	return "", errRedirectLoop
+}
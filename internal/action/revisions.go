@@ -1,11 +1,15 @@
 package action
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"github.com/akikareha/himewiki/internal/config"
 	"github.com/akikareha/himewiki/internal/data"
@@ -54,7 +58,23 @@ func Revert(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *
 		return
 	}
 
-	err := data.Revert(params.DbName, *params.ID)
+	content, err := data.LoadRevision(params.DbName, *params.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load revision", http.StatusInternalServerError)
+		return
+	}
+
+	meta := requestMeta(r, params)
+	meta.Summary = "Revert to r" + strconv.Itoa(*params.ID)
+	err = data.Revert(params.DbName, *params.ID, PageLinks(cfg, content), meta)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to revert", http.StatusInternalServerError)
 		return
@@ -75,7 +95,7 @@ func ViewRevision(cfg *config.Config, w http.ResponseWriter, r *http.Request, pa
 		return
 	}
 
-	title, _, _, rendered := format.Apply(cfg, params.DbName, content)
+	title, _, _, rendered, _ := format.ApplyDisplay(cfg, params.DbName, content)
 
 	_, current, _ := data.Load(params.DbName)
 	diffText := util.Diff(current, content)
@@ -93,6 +113,7 @@ func ViewRevision(cfg *config.Config, w http.ResponseWriter, r *http.Request, pa
 		Rendered   template.HTML
 		ID         int
 		Diff       string
+		CSRF       string
 	}{
 		SiteName:   cfg.Site.Name,
 		Name:       params.Name,
@@ -101,6 +122,131 @@ func ViewRevision(cfg *config.Config, w http.ResponseWriter, r *http.Request, pa
 		Rendered:   template.HTML(rendered),
 		ID:         *params.ID,
 		Diff:       diffText,
+		CSRF:       csrfToken(cfg, w, r),
 	}
 	templates.Render(w, "revision", data)
 }
+
+// Compare shows difference between any two revisions of page
+// as unified diff or side by side.
+func Compare(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	query := r.URL.Query()
+	fromID, err := strconv.Atoi(query.Get("from"))
+	if err != nil || fromID <= 0 {
+		http.Error(w, "Bad revision id", http.StatusBadRequest)
+		return
+	}
+	toID, err := strconv.Atoi(query.Get("to"))
+	if err != nil || toID <= 0 {
+		http.Error(w, "Bad revision id", http.StatusBadRequest)
+		return
+	}
+	// always compare older revision to newer one
+	if fromID > toID {
+		fromID, toID = toID, fromID
+	}
+
+	fromContent, err := data.LoadRevision(params.DbName, fromID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	toContent, err := data.LoadRevision(params.DbName, toID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	side := query.Get("view") == "side"
+	diffText := ""
+	sideHTML := ""
+	if side {
+		sideHTML = format.SideBySide(util.SideBySide(fromContent, toContent))
+	} else {
+		diffText = util.Diff(fromContent, toContent)
+	}
+
+	data := struct {
+		SiteName   string
+		Name       string
+		Title      string
+		From       int
+		To         int
+		Side       bool
+		Diff       string
+		SideBySide template.HTML
+	}{
+		SiteName:   cfg.Site.Name,
+		Name:       params.Name,
+		Title:      params.DbName,
+		From:       fromID,
+		To:         toID,
+		Side:       side,
+		Diff:       diffText,
+		SideBySide: template.HTML(sideHTML),
+	}
+	templates.Render(w, "compare", data)
+}
+
+type blameLine struct {
+	Text      string
+	ID        int
+	Author    string
+	CreatedAt time.Time
+}
+
+// Blame shows which revision last changed each line of page.
+func Blame(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	revisionID, content, err := data.Load(params.DbName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	revs, err := data.LoadHistory(params.DbName)
+	if err != nil {
+		http.Error(w, "Failed to load revisions", http.StatusInternalServerError)
+		return
+	}
+
+	// current content may be older revision after revert
+	if len(revs) < 1 || revs[len(revs)-1].ID != revisionID {
+		current := data.Revision{ID: revisionID, Content: content}
+		for _, rev := range revs {
+			if rev.ID == revisionID {
+				current = rev
+			}
+		}
+		revs = append(revs, current)
+	}
+
+	versions := make([]string, len(revs))
+	for i, rev := range revs {
+		versions[i] = rev.Content
+	}
+	texts, origins := util.Blame(versions)
+
+	lines := make([]blameLine, len(texts))
+	for i, text := range texts {
+		rev := revs[origins[i]]
+		lines[i] = blameLine{
+			Text:      text,
+			ID:        rev.ID,
+			Author:    rev.Meta.Author,
+			CreatedAt: rev.CreatedAt,
+		}
+	}
+
+	data := struct {
+		SiteName string
+		Name     string
+		Title    string
+		Lines    []blameLine
+	}{
+		SiteName: cfg.Site.Name,
+		Name:     params.Name,
+		Title:    params.DbName,
+		Lines:    lines,
+	}
+	templates.Render(w, "blame", data)
+}
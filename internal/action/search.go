@@ -1,7 +1,9 @@
 package action
 
 import (
+	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -9,9 +11,75 @@ import (
 
 	"github.com/akikareha/himewiki/internal/config"
 	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/format/ast"
 	"github.com/akikareha/himewiki/internal/templates"
+	"github.com/akikareha/himewiki/internal/util"
 )
 
+const snippetLength = 160
+
+type searchHit struct {
+	Name    string
+	Path    string
+	Date    string
+	Snippet template.HTML
+}
+
+// searchText searches pages by query with snippets of matches.
+func searchText(cfg *config.Config, query util.Query, page int) ([]searchHit, error) {
+	records, err := data.SearchText(query, page, perBigPage)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]searchHit, 0, len(records))
+	for _, r := range records {
+		plain := format.Plain(cfg, r.Content)
+		hits = append(hits, searchHit{
+			Name:    r.Name,
+			Path:    ast.PagePath(r.Name),
+			Snippet: template.HTML(format.Snippet(plain, query.Terms, snippetLength)),
+		})
+	}
+	return hits, nil
+}
+
+// searchHistory searches past revisions by query
+// with links to matching revisions.
+func searchHistory(cfg *config.Config, query util.Query, page int) ([]searchHit, error) {
+	revs, err := data.SearchHistory(query, page, perBigPage)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]searchHit, 0, len(revs))
+	for _, rev := range revs {
+		plain := format.Plain(cfg, rev.Content)
+		hits = append(hits, searchHit{
+			Name:    rev.Name,
+			Path:    ast.PagePath(rev.Name) + "?a=rev&i=" + strconv.Itoa(rev.ID),
+			Date:    rev.CreatedAt.Format("2006-01-02 15:04"),
+			Snippet: template.HTML(format.Snippet(plain, query.Terms, snippetLength)),
+		})
+	}
+	return hits, nil
+}
+
+// searchImages searches image names by query.
+func searchImages(query util.Query, page int) ([]searchHit, error) {
+	names, err := data.SearchImages(query, page, perBigPage)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]searchHit, 0, len(names))
+	for _, name := range names {
+		hits = append(hits, searchHit{
+			Name: name,
+			Path: "/" + url.PathEscape(name),
+		})
+	}
+	return hits, nil
+}
+
 func Search(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
 	pageStr := r.URL.Query().Get("p")
 	page, err := strconv.Atoi(pageStr)
@@ -23,15 +91,26 @@ func Search(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *
 	word := norm.NFC.String(rawWord)
 	searchType := r.URL.Query().Get("t")
 	var results []string
+	var hits []searchHit
 	if word != "" {
-		if searchType == "name" {
-			results, _ = data.SearchNames(word, page, perBigPage)
+		if searchType == "text" {
+			hits, err = searchText(cfg, util.ParseQuery(word), page)
+		} else if searchType == "history" {
+			hits, err = searchHistory(cfg, util.ParseQuery(word), page)
+		} else if searchType == "image" {
+			hits, err = searchImages(util.ParseQuery(word), page)
+		} else if searchType == "name" {
+			results, err = data.SearchNames(word, page, perBigPage)
 		} else if searchType == "content" {
-			results, _ = data.SearchContents(word, page, perBigPage)
+			results, err = data.SearchContents(word, page, perBigPage)
 		} else {
 			http.NotFound(w, r)
 			return
 		}
+		if err != nil {
+			http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+			return
+		}
 	}
 	for i := 0; i < len(results); i++ {
 		r := results[i]
@@ -49,12 +128,14 @@ func Search(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *
 		Type     string
 		Word     string
 		Results  []string
+		Hits     []searchHit
 		NextPage int
 	}{
 		SiteName: cfg.Site.Name,
 		Type:     searchType,
 		Word:     word,
 		Results:  results,
+		Hits:     hits,
 		NextPage: page + 1,
 	}
 	templates.Render(w, "search", data)
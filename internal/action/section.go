@@ -0,0 +1,171 @@
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/filter"
+	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/templates"
+	"github.com/akikareha/himewiki/internal/util"
+)
+
+// sectionHash identifies section text to detect edit conflicts.
+func sectionHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// EditSection edits only one section of page.
+// Edit conflicts are detected by text of the section,
+// so concurrent edits on different sections succeed.
+func EditSection(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params, section int) {
+	revisionID, page, err := data.Load(params.DbName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	_, current, _, ok := format.SplitSection(cfg, page, section)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var previewed bool
+	var baseHash string
+	var content string
+	var preview string
+	var save string
+	meta := formMeta(r, params)
+	if r.Method != http.MethodPost {
+		previewed = false
+		baseHash = sectionHash(current)
+		content = current
+		preview = ""
+		save = ""
+	} else {
+		previewed = r.FormValue("previewed") == "true"
+		baseHash = r.FormValue("base_hash")
+		rawContent := r.FormValue("content")
+		content = norm.NFC.String(rawContent)
+		preview = r.FormValue("preview")
+		save = r.FormValue("save")
+	}
+
+	var filtered string
+	if previewed && save != "" {
+		filtered, err = filter.Apply(cfg, params.DbName, content)
+	} else {
+		filtered, err = content, nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to filter content", http.StatusInternalServerError)
+		return
+	}
+	if filtered != content {
+		meta.Source = data.SourceFilter
+	}
+	// HTML is not shown when saving
+	apply := format.ApplyDisplay
+	if previewed && save != "" {
+		apply = format.Apply
+	}
+	title, normalized, _, rendered, _ := apply(cfg, params.DbName, filtered)
+
+	diffText := ""
+	conflict := false
+	if previewed && save != "" {
+		for tries := 1; ; tries++ {
+			if sectionHash(current) != baseHash || tries > saveTries {
+				// keep user's text and show what others changed
+				conflict = true
+				previewed = false
+				baseHash = sectionHash(current)
+				diffText = util.Diff(current, normalized)
+				break
+			}
+
+			spliced, _ := format.ReplaceSection(cfg, page, section, normalized)
+			_, spliced, _, _, _ = format.Apply(cfg, params.DbName, spliced)
+			pageCount, err := data.Save(cfg, params.DbName, spliced, PageLinks(cfg, spliced), revisionID, meta)
+			if errors.Is(err, data.ErrConflict) {
+				// other sections may have changed, so splice again
+				revisionID, page, err = data.Load(params.DbName)
+				if err != nil {
+					http.Error(w, "Failed to load page", http.StatusInternalServerError)
+					return
+				}
+				_, current, _, ok = format.SplitSection(cfg, page, section)
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				continue
+			}
+			if err != nil {
+				http.Error(w, "Failed to save", http.StatusInternalServerError)
+				return
+			}
+
+			scheduleGnome(cfg, pageCount)
+
+			http.Redirect(w, r, "/"+url.PathEscape(params.Name)+"?b=diff", http.StatusFound)
+			return
+		}
+	} else if preview != "" {
+		previewed = true
+		diffText = util.Diff(current, normalized)
+	}
+
+	searchName := params.Name
+	if strings.HasSuffix(searchName, ".wiki") {
+		searchName = searchName[:len(searchName)-5]
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	data := struct {
+		SiteName   string
+		Name       string
+		Previewed  bool
+		Conflict   bool
+		RevisionID int
+		Section    int
+		BaseHash   string
+		Text       string
+		Summary    string
+		Minor      bool
+		Title      string
+		SearchName string
+		Rendered   template.HTML
+		Diff       string
+		CSRF       string
+	}{
+		SiteName:   cfg.Site.Name,
+		Name:       params.Name,
+		Previewed:  previewed,
+		Conflict:   conflict,
+		RevisionID: revisionID,
+		Section:    section,
+		BaseHash:   baseHash,
+		Text:       normalized,
+		Summary:    meta.Summary,
+		Minor:      meta.Minor,
+		Title:      title,
+		SearchName: searchName,
+		Rendered:   template.HTML(rendered),
+		Diff:       diffText,
+		CSRF:       csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "edit", data)
+}
@@ -0,0 +1,218 @@
+package action
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/oidc"
+)
+
+const (
+	ssoCookie     = "oidc"
+	ssoCookieTTL  = 10 * time.Minute
+	ssoTimeout    = 30 * time.Second
+	defaultClaim  = "preferred_username"
+	ssoLoginError = "Failed to sign in with SSO."
+)
+
+var (
+	ssoMu       sync.Mutex
+	ssoProvider *oidc.Provider
+)
+
+// provider returns OpenID provider,
+// discovering it on first use or after failure.
+func provider(ctx context.Context, cfg *config.Config) (*oidc.Provider, error) {
+	ssoMu.Lock()
+	defer ssoMu.Unlock()
+
+	if ssoProvider != nil {
+		return ssoProvider, nil
+	}
+	p, err := oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       cfg.OIDC.Issuer,
+		ClientID:     cfg.OIDC.ClientID,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  strings.TrimSuffix(cfg.Site.Base, "/") + "/?a=oidccallback",
+		Scopes:       cfg.OIDC.Scopes,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	ssoProvider = p
+	return p, nil
+}
+
+// ssoRole maps values of role claim to highest matching role,
+// or default role when none matches. It also reports whether
+// role claim is configured and present, so that role given by
+// the provider should override role stored for the user.
+func ssoRole(cfg *config.Config, claims oidc.Claims) (data.Role, bool) {
+	role, ok := data.ParseRole(cfg.OIDC.DefaultRole)
+	if !ok {
+		role = data.RoleEditor
+	}
+	if cfg.OIDC.RoleClaim == "" {
+		return role, false
+	}
+	if _, ok := claims[cfg.OIDC.RoleClaim]; !ok {
+		return role, false
+	}
+
+	matched := false
+	for _, value := range claims.Values(cfg.OIDC.RoleClaim) {
+		for _, mapping := range cfg.OIDC.Roles {
+			if mapping.Value != value {
+				continue
+			}
+			r, ok := data.ParseRole(mapping.Role)
+			if !ok {
+				continue
+			}
+			if !matched || r > role {
+				role = r
+				matched = true
+			}
+		}
+	}
+	return role, true
+}
+
+// SSO starts sign in with OpenID provider.
+func SSO(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if cfg.OIDC.Issuer == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ssoTimeout)
+	defer cancel()
+	p, err := provider(ctx, cfg)
+	if err != nil {
+		http.Error(w, "Failed to reach identity provider", http.StatusBadGateway)
+		log.Println("failed to discover identity provider:", err)
+		return
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		secrets[i], err = oidc.RandomString()
+		if err != nil {
+			http.Error(w, "Failed to start sign in", http.StatusInternalServerError)
+			return
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+	returnTo := base64.RawURLEncoding.EncodeToString([]byte(returnPath(r)))
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Value:    strings.Join([]string{state, nonce, verifier, returnTo}, "."),
+		Path:     "/",
+		MaxAge:   int(ssoCookieTTL / time.Second),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.Site.Base, "https:"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, p.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// SSOCallback finishes sign in with OpenID provider
+// and starts session.
+func SSOCallback(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if cfg.OIDC.Issuer == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(ssoCookie)
+	http.SetCookie(w, &http.Cookie{Name: ssoCookie, Path: "/", MaxAge: -1})
+	if err != nil {
+		renderLogin(cfg, w, r, "", "/", ssoLoginError)
+		return
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 4 {
+		renderLogin(cfg, w, r, "", "/", ssoLoginError)
+		return
+	}
+	state, nonce, verifier := parts[0], parts[1], parts[2]
+	returnTo := "/"
+	if returnBytes, err := base64.RawURLEncoding.DecodeString(parts[3]); err == nil {
+		returnTo = localPath(string(returnBytes))
+	}
+
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+		renderLogin(cfg, w, r, "", returnTo, ssoLoginError)
+		return
+	}
+	if query.Get("error") != "" {
+		log.Println("identity provider returned error:", query.Get("error"))
+		renderLogin(cfg, w, r, "", returnTo, ssoLoginError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ssoTimeout)
+	defer cancel()
+	p, err := provider(ctx, cfg)
+	if err != nil {
+		http.Error(w, "Failed to reach identity provider", http.StatusBadGateway)
+		log.Println("failed to discover identity provider:", err)
+		return
+	}
+	rawToken, err := p.Exchange(ctx, query.Get("code"), verifier)
+	if err != nil {
+		log.Println("failed to exchange code:", err)
+		renderLogin(cfg, w, r, "", returnTo, ssoLoginError)
+		return
+	}
+	claims, err := p.Verify(ctx, rawToken, nonce)
+	if err != nil {
+		log.Println("failed to verify id token:", err)
+		renderLogin(cfg, w, r, "", returnTo, ssoLoginError)
+		return
+	}
+
+	claim := cfg.OIDC.UsernameClaim
+	if claim == "" {
+		claim = defaultClaim
+	}
+	name := norm.NFC.String(claims.String(claim))
+	if !validUserName(name) {
+		renderLogin(cfg, w, r, "", returnTo, "User name given by identity provider is invalid.")
+		return
+	}
+
+	role, mapped := ssoRole(cfg, claims)
+	name, err = data.SignInExternal(cfg.OIDC.Issuer, claims.String("sub"), name, role, mapped)
+	if errors.Is(err, data.ErrUserExists) {
+		renderLogin(cfg, w, r, "", returnTo, "User name is already used by other account.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+
+	ttl := sessionTTL(cfg)
+	token, err := data.CreateSession(name, ttl)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(cfg, w, token, ttl)
+
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
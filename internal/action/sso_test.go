@@ -0,0 +1,50 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/oidc"
+)
+
+func TestSSORole(t *testing.T) {
+	tests := []struct {
+		name        string
+		roleClaim   string
+		defaultRole string
+		claims      oidc.Claims
+		want        data.Role
+		wantMapped  bool
+	}{
+		{"no role claim configured", "", "", oidc.Claims{"groups": "wiki-admins"}, data.RoleEditor, false},
+		{"default role", "", "reader", oidc.Claims{}, data.RoleReader, false},
+		{"bad default role", "", "boss", oidc.Claims{}, data.RoleEditor, false},
+		{"claim absent", "groups", "reader", oidc.Claims{"sub": "1"}, data.RoleReader, false},
+		{"string claim", "groups", "", oidc.Claims{"groups": "wiki-mods"}, data.RoleModerator, true},
+		{"list claim", "groups", "", oidc.Claims{"groups": []any{"staff", "wiki-admins"}}, data.RoleAdmin, true},
+		{"highest of several", "groups", "", oidc.Claims{"groups": []any{"wiki-admins", "wiki-mods"}}, data.RoleAdmin, true},
+		{"no mapping matched", "groups", "reader", oidc.Claims{"groups": []any{"staff"}}, data.RoleReader, true},
+		{"empty list", "groups", "", oidc.Claims{"groups": []any{}}, data.RoleEditor, true},
+		{"lower than default", "groups", "moderator", oidc.Claims{"groups": "wiki-readers"}, data.RoleReader, true},
+		{"bad role in mapping", "groups", "", oidc.Claims{"groups": "wiki-bosses"}, data.RoleEditor, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.OIDC.RoleClaim = tt.roleClaim
+			cfg.OIDC.DefaultRole = tt.defaultRole
+			cfg.OIDC.Roles = []config.RoleMapping{
+				{Value: "wiki-readers", Role: "reader"},
+				{Value: "wiki-mods", Role: "moderator"},
+				{Value: "wiki-admins", Role: "admin"},
+				{Value: "wiki-bosses", Role: "boss"},
+			}
+			got, mapped := ssoRole(cfg, tt.claims)
+			if got != tt.want || mapped != tt.wantMapped {
+				t.Errorf("ssoRole() = %v, %v; want %v, %v", got, mapped, tt.want, tt.wantMapped)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package action
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+)
+
+const maxSuggestions = 10
+
+// Suggest returns page names completing word
+// in OpenSearch suggestions format.
+func Suggest(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	word := norm.NFC.String(r.URL.Query().Get("w"))
+	names := []string{}
+	if word != "" {
+		found, err := data.SuggestNames(word, maxSuggestions)
+		if err != nil {
+			http.Error(w, "Failed to suggest", http.StatusInternalServerError)
+			log.Println("failed to suggest:", err)
+			return
+		}
+		names = append(names, found...)
+	}
+
+	w.Header().Set("Content-Type", "application/x-suggestions+json")
+	json.NewEncoder(w).Encode([]any{word, names})
+}
+
+type openSearchImage struct {
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Type   string `xml:"type,attr"`
+	URL    string `xml:",chardata"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr"`
+	Template string `xml:"template,attr"`
+}
+
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Image         openSearchImage `xml:"Image"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+// OpenSearch serves OpenSearch description of the wiki
+// so that browsers can add it as a search engine.
+func OpenSearch(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	base := strings.TrimSuffix(cfg.Site.Base, "/") + "/"
+	desc := openSearchDescription{
+		ShortName:     cfg.Site.Name,
+		Description:   "Search " + cfg.Site.Name,
+		InputEncoding: "UTF-8",
+		Image: openSearchImage{
+			Width:  16,
+			Height: 16,
+			Type:   "image/png",
+			URL:    base + "static/icon.png",
+		},
+		URLs: []openSearchURL{
+			{
+				Type:     "text/html",
+				Method:   "get",
+				Template: base + "?a=search&t=text&w={searchTerms}",
+			},
+			{
+				Type:     "application/x-suggestions+json",
+				Method:   "get",
+				Template: base + "?a=suggest&w={searchTerms}",
+			},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(desc); err != nil {
+		log.Println("failed to write opensearch description:", err)
+	}
+}
@@ -0,0 +1,113 @@
+package action
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/data"
+	"github.com/akikareha/himewiki/internal/format/ast"
+	"github.com/akikareha/himewiki/internal/templates"
+)
+
+// Delete moves page into trash after confirmation.
+func Delete(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if _, _, err := data.Load(params.DbName); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		data := struct {
+			SiteName string
+			Name     string
+			Title    string
+			CSRF     string
+		}{
+			SiteName: cfg.Site.Name,
+			Name:     params.Name,
+			Title:    params.DbName,
+			CSRF:     csrfToken(cfg, w, r),
+		}
+		templates.Render(w, "delete", data)
+		return
+	}
+
+	err := data.Delete(params.DbName, requestMeta(r, params))
+	if err != nil {
+		http.Error(w, "Failed to delete", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/?a=trash", http.StatusFound)
+}
+
+func Trash(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	pageStr := r.URL.Query().Get("p")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		page = 1
+	}
+
+	records, err := data.Trash(page, perBigPage)
+	if err != nil {
+		http.Error(w, "Failed to load pages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	data := struct {
+		SiteName string
+		Records  []data.TrashRecord
+		NextPage int
+		CSRF     string
+	}{
+		SiteName: cfg.Site.Name,
+		Records:  records,
+		NextPage: page + 1,
+		CSRF:     csrfToken(cfg, w, r),
+	}
+	templates.Render(w, "trash", data)
+}
+
+func Restore(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid method", http.StatusInternalServerError)
+		return
+	}
+
+	content, err := data.LoadTrashed(params.DbName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = data.Restore(params.DbName, PageLinks(cfg, content), requestMeta(r, params))
+	if err != nil {
+		http.Error(w, "Failed to restore", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, ast.PagePath(params.DbName), http.StatusFound)
+}
+
+// Purge removes deleted page with all of its revisions.
+func Purge(cfg *config.Config, w http.ResponseWriter, r *http.Request, params *Params) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid method", http.StatusInternalServerError)
+		return
+	}
+
+	err := data.Purge(params.DbName)
+	if err != nil {
+		http.Error(w, "Failed to purge", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/?a=trash", http.StatusFound)
+}
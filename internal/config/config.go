@@ -12,10 +12,27 @@ type Link struct {
 	URL string `yaml:"url"`
 }
 
+// RoleMapping maps value of role claim of OpenID Connect to role.
+type RoleMapping struct {
+	Value string `yaml:"value"`
+	Role  string `yaml:"role"`
+}
+
+// Rate is rate limit of requests per client.
+// Zero per-minute means no limit.
+type Rate struct {
+	PerMinute int `yaml:"per-minute"`
+	Burst     int `yaml:"burst"`
+}
+
 type Config struct {
 	App struct {
 		Mode string `yaml:"mode"`
 		Addr string `yaml:"addr"`
+
+		// ProxyHeader is header carrying client address
+		// set by trusted reverse proxy, e.g. X-Real-IP.
+		ProxyHeader string `yaml:"proxy-header"`
 	} `yaml:"app"`
 
 	Database struct {
@@ -43,6 +60,7 @@ type Config struct {
 	Wiki struct {
 		Front  string `yaml:"front"`
 		Format string `yaml:"format"`
+		TOC    int    `yaml:"toc"`
 	} `yaml:"wiki"`
 
 	Image struct {
@@ -73,11 +91,40 @@ type Config struct {
 		Recent      int     `yaml:"recent"`
 	} `yaml:"gnome"`
 
+	Auth struct {
+		RequireLogin bool `yaml:"require-login"`
+		Signup       bool `yaml:"signup"`
+		SessionDays  int  `yaml:"session-days"`
+	} `yaml:"auth"`
+
+	OIDC struct {
+		Issuer        string        `yaml:"issuer"`
+		ClientID      string        `yaml:"client-id"`
+		ClientSecret  string        `yaml:"client-secret"`
+		Scopes        []string      `yaml:"scopes"`
+		UsernameClaim string        `yaml:"username-claim"`
+		RoleClaim     string        `yaml:"role-claim"`
+		Roles         []RoleMapping `yaml:"roles"`
+		DefaultRole   string        `yaml:"default-role"`
+	} `yaml:"oidc"`
+
+	RateLimit struct {
+		View   Rate `yaml:"view"`
+		Search Rate `yaml:"search"`
+		Edit   Rate `yaml:"edit"`
+		Upload Rate `yaml:"upload"`
+		AI     Rate `yaml:"ai"`
+	} `yaml:"rate-limit"`
+
 	PromptsPath string `yaml:"prompts-path"`
 
 	Prompts *Prompts
 
 	Links []Link `yaml:"links"`
+
+	// PageExists reports which of page names exist.
+	// It is set at startup to look up database.
+	PageExists func(names []string) (map[string]bool, error) `yaml:"-"`
 }
 
 func Load(path string) *Config {
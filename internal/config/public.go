@@ -10,6 +10,7 @@ type Public struct {
 	Wiki struct {
 		Front  string
 		Format string
+		TOC    int
 	}
 
 	Image struct {
@@ -37,6 +38,30 @@ type Public struct {
 		Recent      int
 	}
 
+	Auth struct {
+		RequireLogin bool
+		Signup       bool
+		SessionDays  int
+	}
+
+	OIDC struct {
+		Issuer        string
+		ClientID      string
+		Scopes        []string
+		UsernameClaim string
+		RoleClaim     string
+		Roles         []RoleMapping
+		DefaultRole   string
+	}
+
+	RateLimit struct {
+		View   Rate
+		Search Rate
+		Edit   Rate
+		Upload Rate
+		AI     Rate
+	}
+
 	Prompts Prompts
 
 	Links []Link
@@ -58,9 +83,11 @@ func Publish(cfg *Config) Public {
 		Wiki: struct {
 			Front  string
 			Format string
+			TOC    int
 		}{
 			Front:  cfg.Wiki.Front,
 			Format: cfg.Wiki.Format,
+			TOC:    cfg.Wiki.TOC,
 		},
 
 		Image: struct {
@@ -105,6 +132,48 @@ func Publish(cfg *Config) Public {
 			Recent:      cfg.Gnome.Recent,
 		},
 
+		Auth: struct {
+			RequireLogin bool
+			Signup       bool
+			SessionDays  int
+		}{
+			RequireLogin: cfg.Auth.RequireLogin,
+			Signup:       cfg.Auth.Signup,
+			SessionDays:  cfg.Auth.SessionDays,
+		},
+
+		OIDC: struct {
+			Issuer        string
+			ClientID      string
+			Scopes        []string
+			UsernameClaim string
+			RoleClaim     string
+			Roles         []RoleMapping
+			DefaultRole   string
+		}{
+			Issuer:        cfg.OIDC.Issuer,
+			ClientID:      cfg.OIDC.ClientID,
+			Scopes:        cfg.OIDC.Scopes,
+			UsernameClaim: cfg.OIDC.UsernameClaim,
+			RoleClaim:     cfg.OIDC.RoleClaim,
+			Roles:         cfg.OIDC.Roles,
+			DefaultRole:   cfg.OIDC.DefaultRole,
+		},
+
+		RateLimit: struct {
+			View   Rate
+			Search Rate
+			Edit   Rate
+			Upload Rate
+			AI     Rate
+		}{
+			View:   cfg.RateLimit.View,
+			Search: cfg.RateLimit.Search,
+			Edit:   cfg.RateLimit.Edit,
+			Upload: cfg.RateLimit.Upload,
+			AI:     cfg.RateLimit.AI,
+		},
+
 		Prompts: *cfg.Prompts,
 
 		Links: cfg.Links,
@@ -17,6 +17,33 @@ import (
 
 var db *pgxpool.Pool
 
+// ErrConflict is returned when page was saved by others
+// after base revision.
+var ErrConflict = errors.New("edit conflict")
+
+// ErrExists is returned when page is moved onto existing page.
+var ErrExists = errors.New("page exists")
+
+// ErrTrashed is returned when page is moved onto page in trash.
+// The page in trash must be restored or purged first.
+var ErrTrashed = errors.New("page in trash")
+
+// Sources of revisions.
+const (
+	SourceHuman  = "human"
+	SourceFilter = "filter"
+	SourceGnome  = "gnome"
+)
+
+// Meta tells who made revision and why.
+type Meta struct {
+	// Author is user name or client address of anonymous user.
+	Author  string
+	Summary string
+	Minor   bool
+	Source  string
+}
+
 const createTablesSql = `
 CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
@@ -38,6 +65,9 @@ CREATE INDEX IF NOT EXISTS idx_pages_updated_at
 
 ALTER TABLE pages SET (autovacuum_enabled = true);
 
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS
+	deleted BOOLEAN NOT NULL DEFAULT false;
+
 CREATE TABLE IF NOT EXISTS revisions (
 	id SERIAL PRIMARY KEY,
 	name TEXT NOT NULL,
@@ -53,6 +83,15 @@ CREATE INDEX IF NOT EXISTS idx_revisions_name_id
 
 ALTER TABLE revisions SET (autovacuum_enabled = false);
 
+ALTER TABLE revisions ADD COLUMN IF NOT EXISTS
+	deleted BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE revisions
+	ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS minor BOOLEAN NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'human';
+
 CREATE TABLE IF NOT EXISTS images (
 	name TEXT PRIMARY KEY,
 	content BYTEA NOT NULL,
@@ -83,6 +122,53 @@ CREATE INDEX IF NOT EXISTS idx_image_revisions_name_id
 
 ALTER TABLE image_revisions SET (autovacuum_enabled = false);
 
+CREATE TABLE IF NOT EXISTS links (
+	source TEXT NOT NULL,
+	target TEXT NOT NULL,
+	inter BOOLEAN NOT NULL DEFAULT false,
+	revision_id INT NOT NULL,
+	PRIMARY KEY (source, target, inter)
+);
+
+CREATE INDEX IF NOT EXISTS idx_links_target
+	ON links (target);
+
+ALTER TABLE links SET (autovacuum_enabled = true);
+
+CREATE TABLE IF NOT EXISTS users (
+	name TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	token_hash TEXT PRIMARY KEY,
+	user_name TEXT NOT NULL REFERENCES users (name) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at
+	ON sessions (expires_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS
+	role TEXT NOT NULL DEFAULT 'editor';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS
+	issuer TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS
+	subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_issuer_subject
+	ON users (issuer, subject);
+
+CREATE TABLE IF NOT EXISTS protections (
+	name TEXT PRIMARY KEY,
+	role TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
 CREATE TABLE IF NOT EXISTS state (
 	id INT PRIMARY KEY DEFAULT 1,
 	boot_counter BIGINT NOT NULL DEFAULT 0,
@@ -159,7 +245,7 @@ func Stat() Info {
 	}
 
 	var pageCount, revisionCount, imageCount, imageRevisionCount int
-	err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM pages").Scan(&pageCount)
+	err = db.QueryRow(context.Background(), "SELECT COUNT(*) FROM pages WHERE NOT deleted").Scan(&pageCount)
 	if err != nil {
 		pageCount = -1
 	}
@@ -192,7 +278,7 @@ func Load(name string) (int, string, error) {
 	var id int
 	var content string
 	err := db.QueryRow(context.Background(),
-		"SELECT revision_id, content FROM pages WHERE name=$1", name).
+		"SELECT revision_id, content FROM pages WHERE name=$1 AND NOT deleted", name).
 		Scan(&id, &content)
 	if err != nil {
 		return 0, "", err
@@ -233,7 +319,7 @@ func LoadPrev(name string) (int, string, error) {
 	}
 }
 
-func Save(cfg *config.Config, name, content string, baseRevID int) (int64, error) {
+func Save(cfg *config.Config, name, content string, links []Link, baseRevID int, meta Meta) (int64, error) {
 	ctx := context.Background()
 	tx, err := db.Begin(ctx)
 	if err != nil {
@@ -241,23 +327,20 @@ func Save(cfg *config.Config, name, content string, baseRevID int) (int64, error
 	}
 	defer tx.Rollback(ctx)
 
+	// deleted page is created again as new page
 	var currentRevID int
-	err = tx.QueryRow(ctx, "SELECT revision_id FROM pages WHERE name=$1", name).
-		Scan(&currentRevID)
+	var deleted bool
+	err = tx.QueryRow(ctx, "SELECT revision_id, deleted FROM pages WHERE name=$1", name).
+		Scan(&currentRevID, &deleted)
 	if err != nil && err != pgx.ErrNoRows {
 		return 0, err
 	}
 
-	if currentRevID != 0 && currentRevID != baseRevID {
-		return 0, fmt.Errorf("edit conflict")
+	if currentRevID != 0 && !deleted && currentRevID != baseRevID {
+		return 0, ErrConflict
 	}
 
-	var newRevID int
-	err = tx.QueryRow(ctx,
-		`INSERT INTO revisions (name, content, created_at)
-		 VALUES ($1, $2, now())
-		 RETURNING id`,
-		name, content).Scan(&newRevID)
+	newRevID, err := insertRevision(ctx, tx, name, content, meta)
 	if err != nil {
 		return 0, err
 	}
@@ -268,12 +351,18 @@ func Save(cfg *config.Config, name, content string, baseRevID int) (int64, error
 		 ON CONFLICT (name) DO UPDATE
 		 SET content=EXCLUDED.content,
 		     revision_id=EXCLUDED.revision_id,
-		     updated_at=now()`,
+		     updated_at=now(),
+		     deleted=false`,
 		name, content, newRevID)
 	if err != nil {
 		return 0, err
 	}
 
+	err = saveLinks(ctx, tx, name, links, newRevID)
+	if err != nil {
+		return 0, err
+	}
+
 	var pageCount int64
 	err = tx.QueryRow(ctx, `
 		UPDATE state
@@ -348,7 +437,7 @@ func LoadAll(page int, perPage int) ([]string, error) {
 	offset := (page - 1) * perPage
 
 	rows, err := db.Query(context.Background(),
-		"SELECT name FROM pages ORDER BY name LIMIT $1 OFFSET $2",
+		"SELECT name FROM pages WHERE NOT deleted ORDER BY name LIMIT $1 OFFSET $2",
 		perPage, offset)
 	if err != nil {
 		return nil, err
@@ -369,9 +458,12 @@ func LoadAll(page int, perPage int) ([]string, error) {
 type RecentRecord struct {
 	Name string
 	Diff string
+	Meta Meta
 }
 
-func Recent(page int, perPage int) ([]RecentRecord, error) {
+// Recent lists recently changed pages.
+// Pages last changed by minor edits or by gnome can be hidden.
+func Recent(page int, perPage int, hideMinor bool, hideBots bool) ([]RecentRecord, error) {
 	if page < 1 {
 		return nil, errors.New("invalid page")
 	}
@@ -384,7 +476,8 @@ func Recent(page int, perPage int) ([]RecentRecord, error) {
 		`SELECT
 			p.name,
 			r1.content AS content,
-			r2.content AS prev_content
+			r2.content AS prev_content,
+			r1.author, r1.summary, r1.minor, r1.source
 		 FROM pages p
 		 JOIN revisions r1 ON r1.id = p.revision_id
 		 LEFT JOIN revisions r2
@@ -397,9 +490,12 @@ func Recent(page int, perPage int) ([]RecentRecord, error) {
 				ORDER BY id DESC
 				LIMIT 1
 			)
+		 WHERE NOT p.deleted
+		 AND NOT ($3 AND r1.minor)
+		 AND NOT ($4 AND r1.source = $5)
 		 ORDER BY updated_at DESC, name ASC
 		 LIMIT $1 OFFSET $2
-		`, perPage, offset)
+		`, perPage, offset, hideMinor, hideBots, SourceGnome)
 	if err != nil {
 		return nil, err
 	}
@@ -409,7 +505,9 @@ func Recent(page int, perPage int) ([]RecentRecord, error) {
 	for rows.Next() {
 		var name, content string
 		var prevContent sql.NullString
-		if err := rows.Scan(&name, &content, &prevContent); err != nil {
+		var meta Meta
+		if err := rows.Scan(&name, &content, &prevContent,
+			&meta.Author, &meta.Summary, &meta.Minor, &meta.Source); err != nil {
 			return nil, err
 		}
 		var diffText string
@@ -418,7 +516,7 @@ func Recent(page int, perPage int) ([]RecentRecord, error) {
 		} else {
 			diffText = util.Diff("", content)
 		}
-		record := RecentRecord{Name: name, Diff: diffText}
+		record := RecentRecord{Name: name, Diff: diffText, Meta: meta}
 		results = append(results, record)
 	}
 	return results, nil
@@ -431,6 +529,7 @@ func RecentNames(limit int) ([]string, error) {
 
 	rows, err := db.Query(context.Background(),
 		`SELECT name FROM pages
+		 WHERE NOT deleted
 		 ORDER BY updated_at DESC, name ASC
 		 LIMIT $1
 		`, limit)
@@ -455,9 +554,27 @@ type Revision struct {
 	Name      string
 	Content   string
 	Diff      string
+	Deleted   bool
+	Meta      Meta
 	CreatedAt time.Time
 }
 
+// insertRevision adds revision of page and returns its id.
+func insertRevision(ctx context.Context, tx pgx.Tx, name, content string, meta Meta) (int, error) {
+	if meta.Source == "" {
+		meta.Source = SourceHuman
+	}
+	var revID int
+	err := tx.QueryRow(ctx,
+		`INSERT INTO revisions
+			(name, content, author, summary, minor, source, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, now())
+		 RETURNING id`,
+		name, content, meta.Author, meta.Summary, meta.Minor, meta.Source).
+		Scan(&revID)
+	return revID, err
+}
+
 func LoadRevisions(name string, page int, perPage int) ([]Revision, error) {
 	if page < 1 {
 		return nil, errors.New("invalid page")
@@ -468,7 +585,8 @@ func LoadRevisions(name string, page int, perPage int) ([]Revision, error) {
 	offset := (page - 1) * perPage
 
 	rows, err := db.Query(context.Background(),
-		`SELECT id, name, content, created_at
+		`SELECT id, name, content, deleted,
+			author, summary, minor, source, created_at
 		 FROM revisions
 		 WHERE name=$1
 		 ORDER BY created_at DESC
@@ -482,7 +600,9 @@ func LoadRevisions(name string, page int, perPage int) ([]Revision, error) {
 	var revs []Revision
 	for rows.Next() {
 		var r Revision
-		if err := rows.Scan(&r.ID, &r.Name, &r.Content, &r.CreatedAt); err != nil {
+		if err := rows.Scan(&r.ID, &r.Name, &r.Content, &r.Deleted,
+			&r.Meta.Author, &r.Meta.Summary, &r.Meta.Minor, &r.Meta.Source,
+			&r.CreatedAt); err != nil {
 			return nil, err
 		}
 		revs = append(revs, r)
@@ -500,20 +620,152 @@ func LoadRevisions(name string, page int, perPage int) ([]Revision, error) {
 	return revs, nil
 }
 
-func Revert(name string, revID int) error {
+// LoadHistory loads all live revisions of page, oldest first.
+func LoadHistory(name string) ([]Revision, error) {
+	rows, err := db.Query(context.Background(),
+		`SELECT id, name, content,
+			author, summary, minor, source, created_at
+		 FROM revisions
+		 WHERE name=$1 AND NOT deleted
+		 ORDER BY id ASC
+		`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.Name, &r.Content,
+			&r.Meta.Author, &r.Meta.Summary, &r.Meta.Minor, &r.Meta.Source,
+			&r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revs = append(revs, r)
+	}
+	return revs, rows.Err()
+}
+
+// Revert saves content of old revision as new revision of page
+// so that history tells who reverted and when.
+func Revert(name string, revID int, links []Link, meta Meta) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	var content string
-	err := db.QueryRow(context.Background(),
-		"SELECT content FROM revisions WHERE id=$1 AND name=$2",
+	err = tx.QueryRow(ctx,
+		"SELECT content FROM revisions WHERE id=$1 AND name=$2 AND NOT deleted",
 		revID, name).Scan(&content)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(context.Background(),
+	// trashed pages come back only by Restore
+	var deleted bool
+	err = tx.QueryRow(ctx, "SELECT deleted FROM pages WHERE name=$1", name).Scan(&deleted)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		return pgx.ErrNoRows
+	}
+
+	newRevID, err := insertRevision(ctx, tx, name, content, meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
 		"UPDATE pages SET content=$1, revision_id=$2, updated_at=now() WHERE name=$3",
-		content, revID, name)
+		content, newRevID, name)
+	if err != nil {
+		return err
+	}
+
+	err = saveLinks(ctx, tx, name, links, newRevID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Move renames page with its revisions, outgoing links and protection.
+// If stub is not empty, it is saved as new page at old name
+// with stubLinks as its outgoing links.
+func Move(from string, to string, stub string, stubLinks []Link, meta Meta) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var deleted bool
+	err = tx.QueryRow(ctx,
+		"SELECT deleted FROM pages WHERE name=$1", to).
+		Scan(&deleted)
+	if err == nil && deleted {
+		return ErrTrashed
+	} else if err == nil {
+		return ErrExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE pages SET name=$1, updated_at=now() WHERE name=$2 AND NOT deleted", to, from)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() < 1 {
+		return pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE revisions SET name=$1 WHERE name=$2", to, from)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE links SET source=$1 WHERE source=$2", to, from)
+	if err != nil {
+		return err
+	}
+
+	// redirect stub at old name stays protected as well
+	err = moveProtection(ctx, tx, from, to, stub != "")
+	if err != nil {
+		return err
+	}
+
+	if stub != "" {
+		stubRevID, err := insertRevision(ctx, tx, from, stub, meta)
+		if err != nil {
+			return err
+		}
 
-	return err
+		_, err = tx.Exec(ctx,
+			`INSERT INTO pages (name, content, revision_id, updated_at)
+			 VALUES ($1, $2, $3, now())`,
+			from, stub, stubRevID)
+		if err != nil {
+			return err
+		}
+
+		err = saveLinks(ctx, tx, from, stubLinks, stubRevID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 func LoadRevision(name string, revID int) (string, error) {
@@ -526,6 +778,50 @@ func LoadRevision(name string, revID int) (string, error) {
 	return content, err
 }
 
+// LoadPrevRevision loads content of revision just before revID.
+// Content is empty if there is no such revision.
+func LoadPrevRevision(name string, revID int) (string, error) {
+	var content string
+	err := db.QueryRow(context.Background(),
+		`SELECT content FROM revisions
+		 WHERE name=$1 AND id<$2
+		 ORDER BY id DESC
+		 LIMIT 1`,
+		name, revID).Scan(&content)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return content, err
+}
+
+// RevisionsBySource lists revisions made by source in time window,
+// newest first.
+// Times are written as "2006-01-02 15:04" in database time zone.
+func RevisionsBySource(source string, from string, to string) ([]Revision, error) {
+	rows, err := db.Query(context.Background(),
+		`SELECT id, name, content, created_at
+		 FROM revisions
+		 WHERE source=$1 AND NOT deleted
+		 AND created_at >= $2::timestamp AND created_at < $3::timestamp
+		 ORDER BY id DESC
+		`, source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revs []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.Name, &r.Content, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.Meta.Source = source
+		revs = append(revs, r)
+	}
+	return revs, rows.Err()
+}
+
 func SearchNames(word string, page int, perPage int) ([]string, error) {
 	if page < 1 {
 		return nil, errors.New("invalid page")
@@ -536,7 +832,7 @@ func SearchNames(word string, page int, perPage int) ([]string, error) {
 	offset := (page - 1) * perPage
 
 	rows, err := db.Query(context.Background(),
-		`SELECT name FROM pages WHERE name ILIKE '%' || $1 || '%'
+		`SELECT name FROM pages WHERE name ILIKE '%' || $1 || '%' AND NOT deleted
 		 ORDER BY name
 		 LIMIT $2 OFFSET $3
 		`, word, perPage, offset)
@@ -566,7 +862,7 @@ func SearchContents(word string, page int, perPage int) ([]string, error) {
 	offset := (page - 1) * perPage
 
 	rows, err := db.Query(context.Background(),
-		`SELECT name FROM pages WHERE content ILIKE '%' || $1 || '%'
+		`SELECT name FROM pages WHERE content ILIKE '%' || $1 || '%' AND NOT deleted
 		 ORDER BY name
 		 LIMIT $2 OFFSET $3
 		`, word, perPage, offset)
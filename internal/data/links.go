@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Link is outgoing link of page.
+type Link struct {
+	// Target is page name of wiki link
+	// or "key:path" of interwiki link.
+	Target string
+
+	// Inter tells link is interwiki link.
+	Inter bool
+}
+
+// saveLinks replaces outgoing links of page by links.
+func saveLinks(ctx context.Context, tx pgx.Tx, name string, links []Link, revID int) error {
+	_, err := tx.Exec(ctx, "DELETE FROM links WHERE source=$1", name)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO links (source, target, inter, revision_id)
+			 VALUES ($1, $2, $3, $4)`,
+			name, link.Target, link.Inter, revID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildLinks fills links table from current pages
+// when the table is empty, e.g. just after it was added.
+// Links of content are listed by linksOf.
+func RebuildLinks(linksOf func(content string) []Link) error {
+	ctx := context.Background()
+
+	var empty bool
+	err := db.QueryRow(ctx, "SELECT NOT EXISTS (SELECT 1 FROM links)").Scan(&empty)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return nil
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "SELECT name, content, revision_id FROM pages WHERE NOT deleted")
+	if err != nil {
+		return err
+	}
+	type page struct {
+		name    string
+		content string
+		revID   int
+	}
+	var pages []page
+	for rows.Next() {
+		var p page
+		if err := rows.Scan(&p.name, &p.content, &p.revID); err != nil {
+			rows.Close()
+			return err
+		}
+		pages = append(pages, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range pages {
+		err = saveLinks(ctx, tx, p.name, linksOf(p.content), p.revID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Backlinks lists pages linking to the page.
+func Backlinks(name string) ([]string, error) {
+	rows, err := db.Query(context.Background(),
+		`SELECT source FROM links
+		 WHERE target=$1 AND NOT inter AND source<>$1
+		 ORDER BY source
+		`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, err
+		}
+		results = append(results, source)
+	}
+	return results, nil
+}
+
+// Orphans lists pages no other pages link to.
+func Orphans(page int, perPage int) ([]string, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := db.Query(context.Background(),
+		`SELECT p.name FROM pages p
+		 WHERE NOT p.deleted
+		 AND NOT EXISTS (
+			SELECT 1 FROM links l
+			WHERE l.target = p.name
+			AND NOT l.inter
+			AND l.source <> p.name
+		 )
+		 ORDER BY p.name
+		 LIMIT $1 OFFSET $2
+		`, perPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		results = append(results, name)
+	}
+	return results, nil
+}
+
+type WantedRecord struct {
+	Name  string
+	Count int
+}
+
+// Wanted lists pages linked from other pages but not existing,
+// most linked first.
+func Wanted(page int, perPage int) ([]WantedRecord, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := db.Query(context.Background(),
+		`SELECT l.target, COUNT(*) AS count
+		 FROM links l
+		 WHERE NOT l.inter
+		 AND NOT EXISTS (
+			SELECT 1 FROM pages p
+			WHERE p.name = l.target AND NOT p.deleted
+		 )
+		 GROUP BY l.target
+		 ORDER BY count DESC, l.target ASC
+		 LIMIT $1 OFFSET $2
+		`, perPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []WantedRecord
+	for rows.Next() {
+		var r WantedRecord
+		if err := rows.Scan(&r.Name, &r.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// PagesExist reports which of page names exist.
+func PagesExist(names []string) (map[string]bool, error) {
+	rows, err := db.Query(context.Background(),
+		"SELECT name FROM pages WHERE name = ANY($1) AND NOT deleted", names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		found[name] = true
+	}
+	return found, rows.Err()
+}
@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Role is level of what user may do. Higher role can do all
+// that lower roles can.
+type Role int
+
+const (
+	RoleReader Role = iota
+	RoleEditor
+	RoleModerator
+	RoleAdmin
+)
+
+var roleNames = []string{"reader", "editor", "moderator", "admin"}
+
+// Roles lists all roles from lowest.
+var Roles = []Role{RoleReader, RoleEditor, RoleModerator, RoleAdmin}
+
+func (r Role) String() string {
+	if r < 0 || int(r) >= len(roleNames) {
+		return "unknown"
+	}
+	return roleNames[r]
+}
+
+// ParseRole parses role name.
+func ParseRole(name string) (Role, bool) {
+	for i, n := range roleNames {
+		if n == name {
+			return Role(i), true
+		}
+	}
+	return RoleReader, false
+}
+
+// ErrNoUser is returned when user does not exist.
+var ErrNoUser = errors.New("no such user")
+
+// UserRole returns role of user.
+func UserRole(name string) (Role, error) {
+	var roleName string
+	err := db.QueryRow(context.Background(),
+		"SELECT role FROM users WHERE name=$1", name).Scan(&roleName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RoleReader, ErrNoUser
+	}
+	if err != nil {
+		return RoleReader, err
+	}
+	role, _ := ParseRole(roleName)
+	return role, nil
+}
+
+// SetUserRole changes role of user.
+func SetUserRole(name string, role Role) error {
+	tag, err := db.Exec(context.Background(),
+		"UPDATE users SET role=$1 WHERE name=$2", role.String(), name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() < 1 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+type UserRecord struct {
+	Name string
+	Role Role
+}
+
+// Users lists users by name.
+func Users(page int, perPage int) ([]UserRecord, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := db.Query(context.Background(),
+		`SELECT name, role FROM users
+		 ORDER BY name
+		 LIMIT $1 OFFSET $2
+		`, perPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []UserRecord
+	for rows.Next() {
+		var r UserRecord
+		var roleName string
+		if err := rows.Scan(&r.Name, &roleName); err != nil {
+			return nil, err
+		}
+		r.Role, _ = ParseRole(roleName)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// dbtx is database or transaction.
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func loadProtection(ctx context.Context, q dbtx, name string) (Role, error) {
+	var roleName string
+	err := q.QueryRow(ctx,
+		"SELECT role FROM protections WHERE name=$1", name).Scan(&roleName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RoleEditor, nil
+	}
+	if err != nil {
+		return RoleEditor, err
+	}
+	role, _ := ParseRole(roleName)
+	return role, nil
+}
+
+func storeProtection(ctx context.Context, q dbtx, name string, role Role) error {
+	if role <= RoleEditor {
+		_, err := q.Exec(ctx, "DELETE FROM protections WHERE name=$1", name)
+		return err
+	}
+
+	_, err := q.Exec(ctx,
+		`INSERT INTO protections (name, role, updated_at)
+		 VALUES ($1, $2, now())
+		 ON CONFLICT (name) DO UPDATE
+		 SET role=EXCLUDED.role, updated_at=now()`,
+		name, role.String())
+	return err
+}
+
+// Protection returns role needed to change page.
+// Pages without protection need editor.
+func Protection(name string) (Role, error) {
+	return loadProtection(context.Background(), db, name)
+}
+
+// SetProtection sets role needed to change page.
+// Protection by editor or lower is removed.
+func SetProtection(name string, role Role) error {
+	return storeProtection(context.Background(), db, name, role)
+}
+
+// moveProtection carries protection of page from to page to,
+// keeping higher one if to is also protected.
+// Protection of from is kept only when keep is true.
+func moveProtection(ctx context.Context, tx pgx.Tx, from string, to string, keep bool) error {
+	fromRole, err := loadProtection(ctx, tx, from)
+	if err != nil {
+		return err
+	}
+	toRole, err := loadProtection(ctx, tx, to)
+	if err != nil {
+		return err
+	}
+	if fromRole > toRole {
+		if err := storeProtection(ctx, tx, to, fromRole); err != nil {
+			return err
+		}
+	}
+	if keep {
+		return nil
+	}
+	return storeProtection(ctx, tx, from, RoleEditor)
+}
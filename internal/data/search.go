@@ -0,0 +1,219 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/util"
+)
+
+var likeEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"%", "\\%",
+	"_", "\\_",
+)
+
+// likePattern makes ILIKE pattern matching term anywhere.
+func likePattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+// sqlArgs collects positional arguments of query being built.
+type sqlArgs []any
+
+func (a *sqlArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// matchConds makes conditions that all terms and none of excludes
+// of query appear in any of columns.
+func matchConds(query util.Query, columns []string, args *sqlArgs) []string {
+	match := func(pat string) string {
+		var ors []string
+		for _, column := range columns {
+			ors = append(ors, column+" ILIKE "+pat)
+		}
+		return "(" + strings.Join(ors, " OR ") + ")"
+	}
+
+	var conds []string
+	for _, term := range query.Terms {
+		conds = append(conds, match(args.add(likePattern(term))))
+	}
+	for _, term := range query.Excludes {
+		conds = append(conds, "NOT "+match(args.add(likePattern(term))))
+	}
+	return conds
+}
+
+type SearchRecord struct {
+	Name    string
+	Content string
+	Rank    float64
+}
+
+// SearchText finds pages having all terms and none of excludes
+// of query in name or content, ignoring case.
+// Pages are ranked by title match and occurrences of terms.
+// Matching is by substring, so it also works for text without spaces
+// such as Japanese, using trigram indexes.
+func SearchText(query util.Query, page int, perPage int) ([]SearchRecord, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	if len(query.Terms) < 1 {
+		return nil, nil
+	}
+	offset := (page - 1) * perPage
+
+	var args sqlArgs
+	conds := append([]string{"NOT deleted"},
+		matchConds(query, []string{"name", "content"}, &args)...)
+	var ranks []string
+	for _, term := range query.Terms {
+		pat := args.add(likePattern(term))
+		lower := args.add(strings.ToLower(term))
+		ranks = append(ranks,
+			"(CASE WHEN lower(name) = "+lower+" THEN 20"+
+				" WHEN name ILIKE "+pat+" THEN 10 ELSE 0 END)"+
+				" + ln(1 + (length(lower(content))"+
+				" - length(replace(lower(content), "+lower+", '')))"+
+				" / length("+lower+")::float8)")
+	}
+
+	sql := "SELECT name, content, " + strings.Join(ranks, " + ") + " AS rank" +
+		" FROM pages WHERE " + strings.Join(conds, " AND ") +
+		" ORDER BY rank DESC, name" +
+		" LIMIT " + args.add(perPage) + " OFFSET " + args.add(offset)
+	rows, err := db.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchRecord
+	for rows.Next() {
+		var r SearchRecord
+		if err := rows.Scan(&r.Name, &r.Content, &r.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// SearchHistory finds revisions having all terms and none of excludes
+// of query in content, including text no longer on pages.
+// Pages in trash are left out. Only latest matching revision of each page is listed,
+// most recent first.
+func SearchHistory(query util.Query, page int, perPage int) ([]Revision, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	if len(query.Terms) < 1 {
+		return nil, nil
+	}
+	offset := (page - 1) * perPage
+
+	var args sqlArgs
+	// revisions of pages in trash are hidden as pages are
+	conds := append([]string{"NOT deleted",
+		"NOT EXISTS (SELECT 1 FROM pages p WHERE p.name = revisions.name AND p.deleted)"},
+		matchConds(query, []string{"content"}, &args)...)
+	sql := "SELECT id, name, content, created_at FROM (" +
+		"SELECT DISTINCT ON (name) id, name, content, created_at" +
+		" FROM revisions WHERE " + strings.Join(conds, " AND ") +
+		" ORDER BY name, id DESC" +
+		") r ORDER BY created_at DESC, id DESC" +
+		" LIMIT " + args.add(perPage) + " OFFSET " + args.add(offset)
+	rows, err := db.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.Name, &r.Content, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// SearchImages finds image names having all terms
+// and none of excludes of query.
+func SearchImages(query util.Query, page int, perPage int) ([]string, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	if len(query.Terms) < 1 {
+		return nil, nil
+	}
+	offset := (page - 1) * perPage
+
+	var args sqlArgs
+	conds := matchConds(query, []string{"name"}, &args)
+	sql := "SELECT name FROM images WHERE " + strings.Join(conds, " AND ") +
+		" ORDER BY name" +
+		" LIMIT " + args.add(perPage) + " OFFSET " + args.add(offset)
+	rows, err := db.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		results = append(results, name)
+	}
+	return results, rows.Err()
+}
+
+// SuggestNames lists page names similar to word for completion,
+// most similar first.
+func SuggestNames(word string, limit int) ([]string, error) {
+	if limit < 1 {
+		return nil, errors.New("invalid limit")
+	}
+
+	rows, err := db.Query(context.Background(),
+		`SELECT name FROM pages
+		 WHERE $1 <% name AND NOT deleted
+		 ORDER BY word_similarity($1, name) DESC,
+		          similarity($1, name) DESC, name
+		 LIMIT $2
+		`, word, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		results = append(results, name)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Delete moves page into trash by saving deletion revision.
+// Revisions are kept so that page can be restored.
+func Delete(name string, meta Meta) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	revID, err := insertRevision(ctx, tx, name, "", meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE revisions SET deleted=true WHERE id=$1", revID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE pages SET content='', revision_id=$1, deleted=true, updated_at=now()
+		 WHERE name=$2 AND NOT deleted`,
+		revID, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() < 1 {
+		return errors.New("no such page")
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM links WHERE source=$1", name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LoadTrashed loads content of last live revision of deleted page,
+// which is brought back by Restore.
+func LoadTrashed(name string) (string, error) {
+	var content string
+	err := db.QueryRow(context.Background(),
+		`SELECT r.content FROM revisions r
+		 JOIN pages p ON p.name = r.name
+		 WHERE r.name=$1 AND p.deleted AND NOT r.deleted
+		 ORDER BY r.id DESC
+		 LIMIT 1`,
+		name).Scan(&content)
+	return content, err
+}
+
+// Restore brings back last live revision of deleted page
+// as new revision with its outgoing links.
+func Restore(name string, links []Link, meta Meta) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var deleted bool
+	err = tx.QueryRow(ctx, "SELECT deleted FROM pages WHERE name=$1", name).
+		Scan(&deleted)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("page is not deleted")
+	}
+
+	var content string
+	err = tx.QueryRow(ctx,
+		`SELECT content FROM revisions
+		 WHERE name=$1 AND NOT deleted
+		 ORDER BY id DESC
+		 LIMIT 1`,
+		name).Scan(&content)
+	if err != nil {
+		return err
+	}
+
+	revID, err := insertRevision(ctx, tx, name, content, meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE pages SET content=$1, revision_id=$2, deleted=false, updated_at=now()
+		 WHERE name=$3`,
+		content, revID, name)
+	if err != nil {
+		return err
+	}
+
+	err = saveLinks(ctx, tx, name, links, revID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Purge removes deleted page and all of its revisions.
+func Purge(name string) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"DELETE FROM pages WHERE name=$1 AND deleted", name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() < 1 {
+		return errors.New("page is not deleted")
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM revisions WHERE name=$1", name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+type TrashRecord struct {
+	Name      string
+	DeletedAt time.Time
+}
+
+// Trash lists deleted pages, recently deleted first.
+func Trash(page int, perPage int) ([]TrashRecord, error) {
+	if page < 1 {
+		return nil, errors.New("invalid page")
+	}
+	if perPage < 1 {
+		return nil, errors.New("invalid perPage")
+	}
+	offset := (page - 1) * perPage
+
+	rows, err := db.Query(context.Background(),
+		`SELECT name, updated_at FROM pages
+		 WHERE deleted
+		 ORDER BY updated_at DESC, name ASC
+		 LIMIT $1 OFFSET $2
+		`, perPage, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TrashRecord
+	for rows.Next() {
+		var r TrashRecord
+		if err := rows.Scan(&r.Name, &r.DeletedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
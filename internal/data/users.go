@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUserExists is returned when user name is already taken.
+var ErrUserExists = errors.New("user exists")
+
+// ErrLogin is returned when user name or password is wrong.
+var ErrLogin = errors.New("invalid user name or password")
+
+// dummyHash is compared when user does not exist
+// so that login takes same time either way.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// CreateUser adds user with bcrypt hash of password.
+func CreateUser(name, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(context.Background(),
+		"INSERT INTO users (name, password_hash) VALUES ($1, $2)",
+		name, string(hash))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrUserExists
+	}
+	return err
+}
+
+// Authenticate checks password of user.
+func Authenticate(name, password string) error {
+	var hash string
+	err := db.QueryRow(context.Background(),
+		"SELECT password_hash FROM users WHERE name=$1", name).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return ErrLogin
+	}
+	if err != nil {
+		return err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrLogin
+	}
+	return nil
+}
+
+// hashToken hashes session token so that leaked table
+// can not be used to sign in.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession starts session of user valid for ttl
+// and returns its token.
+func CreateSession(name string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	ctx := context.Background()
+	_, err := db.Exec(ctx, "DELETE FROM sessions WHERE expires_at < now()")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO sessions (token_hash, user_name, expires_at)
+		 VALUES ($1, $2, now() + $3 * interval '1 second')`,
+		hashToken(token), name, int64(ttl/time.Second))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SessionUser returns user name of session token.
+// It is empty when session does not exist or has expired.
+func SessionUser(token string) (string, error) {
+	var name string
+	err := db.QueryRow(context.Background(),
+		`SELECT user_name FROM sessions
+		 WHERE token_hash=$1 AND expires_at > now()`,
+		hashToken(token)).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return name, err
+}
+
+// DeleteSession ends session of token.
+func DeleteSession(token string) error {
+	_, err := db.Exec(context.Background(),
+		"DELETE FROM sessions WHERE token_hash=$1", hashToken(token))
+	return err
+}
+
+// SignInExternal returns name of user identified by subject
+// at external identity provider of issuer. User is created with name
+// on first sign in, and keeps that name even if the provider
+// later gives other name. Such users have no password.
+// Role is set on creation, and updated on later sign in
+// only when mapped is true. ErrUserExists is returned
+// when new user can not take name.
+func SignInExternal(issuer, subject, name string, role Role, mapped bool) (string, error) {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var found string
+	err = tx.QueryRow(ctx,
+		"SELECT name FROM users WHERE issuer=$1 AND subject=$2",
+		issuer, subject).Scan(&found)
+	if err == nil {
+		if mapped {
+			_, err = tx.Exec(ctx,
+				"UPDATE users SET role=$1 WHERE name=$2", role.String(), found)
+			if err != nil {
+				return "", err
+			}
+		}
+		return found, tx.Commit(ctx)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO users (name, password_hash, issuer, subject, role)
+		 VALUES ($1, '!', $2, $3, $4)
+		 ON CONFLICT (name) DO NOTHING`,
+		name, issuer, subject, role.String())
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() < 1 {
+		return "", ErrUserExists
+	}
+	return name, tx.Commit(ctx)
+}
@@ -2,6 +2,7 @@ package format
 
 import (
 	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
 	"github.com/akikareha/himewiki/internal/format/creole"
 	"github.com/akikareha/himewiki/internal/format/markdown"
 	"github.com/akikareha/himewiki/internal/format/nomark"
@@ -36,18 +37,60 @@ func Detect(cfg *config.Config, text string) string {
 	return "nomark"
 }
 
+// Parse parses input text into document tree.
+func Parse(cfg *config.Config, text string) *ast.Node {
+	return parseAs(cfg, Detect(cfg, text), text)
+}
+
 // Apply applies wiki formatting on input text
-// and returns title, wiki text, plain text, HTML.
-func Apply(cfg *config.Config, title string, text string) (string, string, string, string) {
+// and returns title, wiki text, plain text, HTML and heading outline.
+// Pages of wiki links are not looked up,
+// so use ApplyDisplay for HTML shown to readers.
+func Apply(cfg *config.Config, title string, text string) (
+	string, string, string, string, []ast.Section,
+) {
+	return apply(cfg, title, text, false, false)
+}
+
+// ApplyDisplay is same as Apply
+// but shows wiki links to missing pages as links to edit in HTML.
+func ApplyDisplay(cfg *config.Config, title string, text string) (
+	string, string, string, string, []ast.Section,
+) {
+	return apply(cfg, title, text, true, false)
+}
+
+// ApplyEditable is same as ApplyDisplay
+// but adds section edit links to headings in HTML.
+func ApplyEditable(cfg *config.Config, title string, text string) (
+	string, string, string, string, []ast.Section,
+) {
+	return apply(cfg, title, text, true, true)
+}
+
+func apply(cfg *config.Config, title string, text string, display bool, edit bool) (
+	string, string, string, string, []ast.Section,
+) {
 	mode := Detect(cfg, text)
+	doc := parseAs(cfg, mode, text)
+	outline := ast.Outline(doc)
+
+	// looking up pages costs a query, so only for display
+	var missing map[string]bool
+	if display {
+		missing = ast.MissingLinks(doc, cfg.PageExists)
+	}
+
+	var normalized, plain, html string
 	if mode == "creole" {
 		fc := creole.ToFormatConfig(cfg)
-		return creole.Apply(fc, title, text)
+		title, normalized, plain, html = creole.Render(fc, title, doc, edit, missing)
 	} else if mode == "markdown" {
 		fc := markdown.ToFormatConfig(cfg)
-		return markdown.Apply(fc, title, text)
+		title, normalized, plain, html = markdown.Render(fc, title, doc, edit, missing)
 	} else { // nomark
 		fc := nomark.ToFormatConfig(cfg)
-		return nomark.Apply(fc, title, text)
+		title, normalized, plain, html = nomark.Render(fc, title, doc, edit, missing)
 	}
+	return title, normalized, plain, html, outline
 }
@@ -0,0 +1,117 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+// mockExists reports all pages exist except "Missing".
+func mockExists(names []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	for _, name := range names {
+		found[name] = name != "Missing"
+	}
+	return found, nil
+}
+
+func TestApplyMissing(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantDisplay string
+		wantApply   string
+	}{
+		{
+			"nomark",
+			"!!!!! Title !!!!!\n\nSee [[Missing]] and [[Test]].\n",
+			"<p>\nSee <span class=\"markup\">[[</span><a href=\"/Missing?a=edit\" class=\"link new\">Missing</a><span class=\"markup\">]]</span> and <span class=\"markup\">[[</span><a href=\"/Test\" class=\"link\">Test</a><span class=\"markup\">]]</span>.\n</p>\n",
+			"<p>\nSee <span class=\"markup\">[[</span><a href=\"/Missing\" class=\"link\">Missing</a><span class=\"markup\">]]</span> and <span class=\"markup\">[[</span><a href=\"/Test\" class=\"link\">Test</a><span class=\"markup\">]]</span>.\n</p>\n",
+		},
+		{
+			"creole",
+			"= Title =\n\nSee [[Missing]] and [[Test]].\n",
+			"<p>\nSee <a href=\"/Missing?a=edit\" class=\"link new\">Missing</a> and <a href=\"/Test\" class=\"link\">Test</a>.\n</p>\n",
+			"<p>\nSee <a href=\"/Missing\" class=\"link\">Missing</a> and <a href=\"/Test\" class=\"link\">Test</a>.\n</p>\n",
+		},
+		{
+			"markdown redirect",
+			"# Title\n\n<<redirect Missing>>\n",
+			"<p class=\"redirect\">Redirect to <a href=\"/Missing?a=edit\" class=\"link new\">Missing</a></p>\n",
+			"<p class=\"redirect\">Redirect to <a href=\"/Missing\" class=\"link\">Missing</a></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			looked := false
+			cfg := &config.Config{}
+			cfg.PageExists = func(names []string) (map[string]bool, error) {
+				looked = true
+				return mockExists(names)
+			}
+
+			_, _, _, got, _ := ApplyDisplay(cfg, "WikiPage", tt.text)
+			if got != tt.wantDisplay {
+				t.Errorf("ApplyDisplay(%q) = %q; want %q", tt.text, got, tt.wantDisplay)
+			}
+
+			looked = false
+			_, _, _, got, _ = Apply(cfg, "WikiPage", tt.text)
+			if got != tt.wantApply {
+				t.Errorf("Apply(%q) = %q; want %q", tt.text, got, tt.wantApply)
+			}
+			if looked {
+				t.Errorf("Apply(%q) looked up pages", tt.text)
+			}
+		})
+	}
+}
+
+func TestApplyOutline(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []ast.Section
+	}{
+		{
+			"nomark japanese",
+			"!!!!! Title !!!!!\n\n!!!! はじめに (概要) !!!!\n\n!!! サーバー設定 !!!\n",
+			[]ast.Section{
+				{Level: 2, Title: "はじめに (概要)", ID: "h-はじめに-概要"},
+				{Level: 3, Title: "サーバー設定", ID: "h-サーバー設定"},
+			},
+		},
+		{
+			"creole duplicates",
+			"= Title =\n\n== 設定 ==\n\n=== Notes ===\n\n== 設定 ==\n\n=== Notes ===\n",
+			[]ast.Section{
+				{Level: 2, Title: "設定", ID: "h-設定"},
+				{Level: 3, Title: "Notes", ID: "h-notes"},
+				{Level: 2, Title: "設定", ID: "h-設定-2"},
+				{Level: 3, Title: "Notes", ID: "h-notes-2"},
+			},
+		},
+		{
+			"markdown no headings",
+			"# Title\n\ntext\n",
+			nil,
+		},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, _, got := Apply(cfg, "WikiPage", tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("len(outline) = %d; want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if got[i].Level != want.Level || got[i].Title != want.Title || got[i].ID != want.ID {
+					t.Errorf("outline[%d] = %+v; want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package ast
+
+import (
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PagePath makes URL path of page from its database name.
+// Names with dot get wiki extension so they are not taken as files.
+func PagePath(name string) string {
+	if strings.IndexByte(name, '.') != -1 {
+		name += ".wiki"
+	}
+	return "/" + url.PathEscape(name)
+}
+
+// Style controls format specific parts of HTML output.
+type Style struct {
+	// Markup shows source markup beside formatted text.
+	Markup bool
+
+	// Edit adds section edit links to headings.
+	Edit bool
+
+	// Missing holds page names of wiki links to pages
+	// which do not exist yet.
+	Missing map[string]bool
+}
+
+var textEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+)
+
+type htmlWriter struct {
+	style    Style
+	buf      strings.Builder
+	outline  []Section
+	sections int
+}
+
+func (w *htmlWriter) markup(mark string) {
+	if !w.style.Markup || mark == "" {
+		return
+	}
+	w.buf.WriteString("<span class=\"markup\">")
+	w.buf.WriteString(template.HTMLEscapeString(mark))
+	w.buf.WriteString("</span>")
+}
+
+func (w *htmlWriter) inlines(nodes []*Node) {
+	for _, n := range nodes {
+		w.inline(n)
+	}
+}
+
+func (w *htmlWriter) inline(n *Node) {
+	switch n.Kind {
+	case Text:
+		w.buf.WriteString(textEscaper.Replace(n.Text))
+	case Strong:
+		w.markup(n.Mark)
+		w.buf.WriteString("<strong>")
+		w.inlines(n.Children)
+		w.buf.WriteString("</strong>")
+		if n.Closed {
+			w.markup(n.Mark)
+		}
+	case Emphasis:
+		w.markup(n.Mark)
+		w.buf.WriteString("<em>")
+		w.inlines(n.Children)
+		w.buf.WriteString("</em>")
+		if n.Closed {
+			w.markup(n.Mark)
+		}
+	case InlineMath:
+		w.markup(n.Mark)
+		w.buf.WriteString("<nomark-math class=\"mathjax\">\\(")
+		w.buf.WriteString(template.HTMLEscapeString(n.Text))
+		w.buf.WriteString("\\)</nomark-math>")
+		w.markup(n.EndMark)
+	case WikiLink:
+		w.markup(n.Mark)
+		w.buf.WriteString("<a href=\"")
+		w.buf.WriteString(PagePath(n.Name))
+		if w.style.Missing[n.Name] {
+			w.buf.WriteString("?a=edit\" class=\"link new\">")
+		} else {
+			w.buf.WriteString("\" class=\"link\">")
+		}
+		w.buf.WriteString(template.HTMLEscapeString(n.Name))
+		w.buf.WriteString("</a>")
+		w.markup(n.EndMark)
+	case Link:
+		htmlURL := template.HTMLEscapeString(n.Text)
+		w.buf.WriteString("<a href=\"")
+		w.buf.WriteString(htmlURL)
+		w.buf.WriteString("\" class=\"link\">")
+		w.buf.WriteString(htmlURL)
+		w.buf.WriteString("</a>")
+	case Image:
+		htmlURL := template.HTMLEscapeString(n.Text)
+		w.buf.WriteString("<img src=\"")
+		w.buf.WriteString(htmlURL)
+		w.buf.WriteString("\" alt=\"")
+		w.buf.WriteString(htmlURL)
+		w.buf.WriteString("\" />")
+	case InterLink:
+		w.buf.WriteString("<a href=\"")
+		w.buf.WriteString(n.URL)
+		w.buf.WriteString(template.HTMLEscapeString(n.Name))
+		w.buf.WriteString("\" class=\"link\">")
+		w.buf.WriteString(template.HTMLEscapeString(n.Text))
+		w.buf.WriteString("</a>")
+	}
+}
+
+func (w *htmlWriter) markupParagraph(mark string) {
+	if !w.style.Markup || mark == "" {
+		return
+	}
+	w.buf.WriteString("<p>\n")
+	w.markup(mark)
+	w.buf.WriteString("\n</p>\n")
+}
+
+func (w *htmlWriter) list(n *Node) {
+	tag := "ul"
+	if n.Ordered {
+		tag = "ol"
+	}
+	w.buf.WriteString("<" + tag + ">\n")
+	for _, item := range n.Children {
+		w.buf.WriteString("<li>")
+		if w.style.Markup && item.Mark != "" {
+			w.markup(item.Mark)
+			w.buf.WriteString(" ")
+		}
+		inlines, nested := SplitListItem(item)
+		w.inlines(inlines)
+		if len(nested) > 0 {
+			w.buf.WriteString("\n")
+		}
+		for _, list := range nested {
+			w.list(list)
+		}
+		w.buf.WriteString("</li>\n")
+	}
+	w.buf.WriteString("</" + tag + ">\n")
+}
+
+func (w *htmlWriter) table(n *Node) {
+	columns := TableColumns(n)
+	w.buf.WriteString("<table>\n")
+	for _, row := range n.Children {
+		w.buf.WriteString("<tr>\n")
+		for i := 0; i < columns; i++ {
+			cell := RowCell(row, i)
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+			w.buf.WriteString("<" + tag)
+			if i < len(n.Aligns) && n.Aligns[i] != "" {
+				w.buf.WriteString(" style=\"text-align: ")
+				w.buf.WriteString(n.Aligns[i])
+				w.buf.WriteString(";\"")
+			}
+			w.buf.WriteString(">")
+			w.inlines(cell.Children)
+			w.buf.WriteString("</" + tag + ">\n")
+		}
+		w.buf.WriteString("</tr>\n")
+	}
+	w.buf.WriteString("</table>\n")
+}
+
+func (w *htmlWriter) toc() {
+	if len(w.outline) < 1 {
+		return
+	}
+	w.buf.WriteString("<nav class=\"toc\">\n")
+	var levels []int
+	for _, section := range w.outline {
+		if len(levels) < 1 {
+			w.buf.WriteString("<ul>\n")
+			levels = append(levels, section.Level)
+		} else if section.Level > levels[len(levels)-1] {
+			w.buf.WriteString("\n<ul>\n")
+			levels = append(levels, section.Level)
+		} else {
+			for len(levels) > 1 && section.Level < levels[len(levels)-1] {
+				w.buf.WriteString("</li>\n</ul>\n")
+				levels = levels[:len(levels)-1]
+			}
+			w.buf.WriteString("</li>\n")
+		}
+		w.buf.WriteString("<li><a href=\"#")
+		w.buf.WriteString(template.HTMLEscapeString(section.ID))
+		w.buf.WriteString("\">")
+		w.buf.WriteString(template.HTMLEscapeString(section.Title))
+		w.buf.WriteString("</a>")
+	}
+	for len(levels) > 0 {
+		w.buf.WriteString("</li>\n</ul>\n")
+		levels = levels[:len(levels)-1]
+	}
+	w.buf.WriteString("</nav>\n")
+}
+
+func (w *htmlWriter) block(n *Node) {
+	switch n.Kind {
+	case Paragraph:
+		w.buf.WriteString("<p>\n")
+		for _, line := range n.Children {
+			w.buf.WriteString(strings.Repeat("&nbsp;", line.Indent))
+			w.inlines(line.Children)
+			if line.Break {
+				w.buf.WriteString("<br />\n")
+			} else {
+				w.buf.WriteString("\n")
+			}
+		}
+		w.buf.WriteString("</p>\n")
+	case Heading:
+		if n.Title {
+			return
+		}
+		level := strconv.Itoa(n.Level)
+		id := w.outline[w.sections].ID
+		w.sections += 1
+		w.buf.WriteString("<h" + level + " id=\"")
+		w.buf.WriteString(template.HTMLEscapeString(id))
+		w.buf.WriteString("\">")
+		if w.style.Markup && n.Mark != "" {
+			w.markup(n.Mark)
+			w.buf.WriteString(" ")
+		}
+		w.buf.WriteString(template.HTMLEscapeString(n.Text))
+		if w.style.Markup && n.Mark != "" {
+			w.buf.WriteString(" ")
+			w.markup(n.Mark)
+		}
+		if w.style.Edit {
+			w.buf.WriteString(" <a href=\"?a=edit&amp;s=")
+			w.buf.WriteString(strconv.Itoa(w.sections))
+			w.buf.WriteString("\" class=\"edit-section\">edit</a>")
+		}
+		w.buf.WriteString("</h" + level + ">\n")
+	case Horizon:
+		if w.style.Markup {
+			w.buf.WriteString("<div class=\"horizon\">\n")
+			w.buf.WriteString(template.HTMLEscapeString(n.Text))
+			w.buf.WriteString("\n</div>\n")
+		} else {
+			w.buf.WriteString("<hr />\n")
+		}
+	case Raw:
+		w.buf.WriteString("<pre><code>")
+		for _, line := range n.Lines {
+			w.buf.WriteString(template.HTMLEscapeString(line))
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString("</code></pre>\n")
+	case Code:
+		w.markupParagraph(n.Mark)
+		w.buf.WriteString("<pre><code>")
+		for _, line := range n.Lines {
+			w.buf.WriteString(template.HTMLEscapeString(line))
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString("</code></pre>\n")
+		w.markupParagraph(n.EndMark)
+	case Math:
+		w.buf.WriteString("<div>\n")
+		if w.style.Markup && n.Mark != "" {
+			w.markup(n.Mark)
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString("<nomark-math class=\"mathjax\">\\[")
+		for _, line := range n.Lines {
+			w.buf.WriteString(template.HTMLEscapeString(line))
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString("\\]</nomark-math>\n")
+		if w.style.Markup && n.EndMark != "" {
+			w.markup(n.EndMark)
+			w.buf.WriteString("\n")
+		}
+		w.buf.WriteString("</div>\n")
+	case List:
+		w.list(n)
+	case Table:
+		w.table(n)
+	case TOC:
+		w.toc()
+	case Redirect:
+		w.buf.WriteString("<p class=\"redirect\">Redirect to ")
+		w.inline(&Node{Kind: WikiLink, Name: n.Name})
+		w.buf.WriteString("</p>\n")
+	}
+}
+
+// HTML renders document tree to HTML.
+func HTML(doc *Node, style Style) string {
+	w := htmlWriter{style: style, outline: Outline(doc)}
+	for _, n := range doc.Children {
+		w.block(n)
+	}
+	return w.buf.String()
+}
@@ -0,0 +1,25 @@
+package ast
+
+import "testing"
+
+func TestPagePath(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"plain", "FrontPage", "/FrontPage"},
+		{"dotted", "Version1.2", "/Version1.2.wiki"},
+		{"space", "Front Page", "/Front%20Page"},
+		{"japanese", "はじめに", "/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB"},
+		{"slash", "a/b.c", "/a%2Fb.c.wiki"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PagePath(tt.page); got != tt.want {
+				t.Errorf("PagePath(%q) = %q; want %q", tt.page, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package ast
+
+// LinkTarget is destination of outgoing link of document.
+type LinkTarget struct {
+	// Name is page name of wiki link
+	// or "key:path" of interwiki link.
+	Name string
+
+	// Inter tells link is interwiki link.
+	Inter bool
+}
+
+// Links lists wiki links and interwiki links of document
+// in document order without duplicates.
+func Links(doc *Node) []LinkTarget {
+	var links []LinkTarget
+	seen := map[LinkTarget]bool{}
+	Walk(doc, func(n *Node) bool {
+		var link LinkTarget
+		switch n.Kind {
+		case WikiLink, Redirect:
+			link = LinkTarget{Name: n.Name}
+		case InterLink:
+			link = LinkTarget{Name: n.Text, Inter: true}
+		default:
+			return true
+		}
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+		return true
+	})
+	return links
+}
+
+// Lookup reports which of page names exist.
+type Lookup func(names []string) (map[string]bool, error)
+
+// MissingLinks looks up pages of wiki links of document at once
+// and returns names of pages which do not exist.
+// If lookup is nil or fails, all pages are taken as existing.
+func MissingLinks(doc *Node, lookup Lookup) map[string]bool {
+	if lookup == nil {
+		return nil
+	}
+	var names []string
+	for _, link := range Links(doc) {
+		if !link.Inter {
+			names = append(names, link.Name)
+		}
+	}
+	if len(names) < 1 {
+		return nil
+	}
+	found, err := lookup(names)
+	if err != nil {
+		return nil
+	}
+	missing := map[string]bool{}
+	for _, name := range names {
+		if !found[name] {
+			missing[name] = true
+		}
+	}
+	return missing
+}
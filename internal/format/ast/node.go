@@ -0,0 +1,126 @@
+// Package ast defines document tree shared by wiki formatters.
+package ast
+
+// Kind is kind of node.
+type Kind int
+
+const (
+	// block nodes
+	Document Kind = iota
+	Blank
+	Paragraph
+	Line
+	Heading
+	Horizon
+	Raw
+	Code
+	Math
+	List
+	ListItem
+	Table
+	TableRow
+	TableCell
+	TOC
+	Redirect
+
+	// inline nodes
+	Text
+	Strong
+	Emphasis
+	InlineMath
+	WikiLink
+	Link
+	Image
+	InterLink
+)
+
+// Node is a node of document tree.
+//
+// Fields are shared by all kinds of nodes.
+// Each kind uses only some of them.
+type Node struct {
+	Kind Kind
+
+	// Text is text content, heading title, link text
+	// or page title of document.
+	Text string
+
+	// Name is page name of wiki link or redirect
+	// or path of interwiki link.
+	Name string
+
+	// URL is URL of link or base URL of interwiki link.
+	URL string
+
+	// Mark and EndMark are source markup
+	// shown in HTML of some formats.
+	Mark    string
+	EndMark string
+
+	// Level is heading level.
+	Level int
+
+	// Offset is byte offset of heading line in source text.
+	Offset int
+
+	// Indent is count of leading spaces of line.
+	Indent int
+
+	// Break tells line ends with hard line break.
+	Break bool
+
+	// Closed tells decoration has closing markup.
+	Closed bool
+
+	// Ordered tells list is numbered.
+	Ordered bool
+
+	// Header tells table cell is header cell.
+	Header bool
+
+	// Title tells heading is used as page title.
+	Title bool
+
+	// Aligns are column alignments of table.
+	Aligns []string
+
+	// Lines are lines of raw, code and math blocks.
+	Lines []string
+
+	Children []*Node
+}
+
+// Append appends child node and returns it.
+func (n *Node) Append(child *Node) *Node {
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// Last returns last child node or nil.
+func (n *Node) Last() *Node {
+	if len(n.Children) < 1 {
+		return nil
+	}
+	return n.Children[len(n.Children)-1]
+}
+
+// AppendText appends text, merging into preceding text node.
+func (n *Node) AppendText(text string) {
+	last := n.Last()
+	if last != nil && last.Kind == Text {
+		last.Text += text
+		return
+	}
+	n.Append(&Node{Kind: Text, Text: text})
+}
+
+// Walk visits node and its descendants in document order.
+// Children are skipped if visit returns false.
+func Walk(n *Node, visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.Children {
+		Walk(child, visit)
+	}
+}
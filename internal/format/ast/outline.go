@@ -0,0 +1,97 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Section is heading in document outline.
+type Section struct {
+	Level  int
+	Title  string
+	ID     string
+	Offset int
+}
+
+// Slug makes anchor id from heading title.
+// Letters and numbers of any script are kept as they are
+// and other characters are joined into single hyphens.
+func Slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() < 1 {
+		return "section"
+	}
+	return b.String()
+}
+
+// headingPrefix keeps heading ids apart from ids of page templates,
+// e.g. heading "Main" and <main id="main">.
+const headingPrefix = "h-"
+
+// Outline lists headings of document except page title.
+// Duplicate ids get numbered suffixes in document order.
+func Outline(doc *Node) []Section {
+	var sections []Section
+	used := map[string]bool{}
+	for _, n := range doc.Children {
+		if n.Kind != Heading || n.Title {
+			continue
+		}
+		base := headingPrefix + Slug(n.Text)
+		id := base
+		for i := 2; used[id]; i++ {
+			id = base + "-" + strconv.Itoa(i)
+		}
+		used[id] = true
+		sections = append(sections, Section{
+			Level:  n.Level,
+			Title:  n.Text,
+			ID:     id,
+			Offset: n.Offset,
+		})
+	}
+	return sections
+}
+
+// InsertTOC inserts table of contents before first heading
+// if document has more than limit headings and has no table of contents.
+// Zero limit disables automatic table of contents.
+func InsertTOC(doc *Node, limit int) {
+	if limit < 1 {
+		return
+	}
+	first := -1
+	count := 0
+	for i, n := range doc.Children {
+		if n.Kind == TOC {
+			return
+		}
+		if n.Kind != Heading || n.Title {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		count += 1
+	}
+	if count <= limit {
+		return
+	}
+
+	children := append([]*Node{}, doc.Children[:first]...)
+	children = append(children, &Node{Kind: TOC})
+	doc.Children = append(children, doc.Children[first:]...)
+}
@@ -0,0 +1,92 @@
+package ast
+
+import "testing"
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"ascii", "Hello, World!", "hello-world"},
+		{"japanese", "はじめに (概要)", "はじめに-概要"},
+		{"prolonged", "サーバー設定", "サーバー設定"},
+		{"symbols", "!!!", "section"},
+		{"numbers", "Version 1.2", "version-1-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slug(tt.title)
+			if got != tt.want {
+				t.Errorf("Slug(%q) = %q; want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func headingDoc(count int) *Node {
+	doc := &Node{Kind: Document}
+	doc.Append(&Node{Kind: Heading, Level: 1, Text: "Title", Title: true})
+	doc.Append(&Node{Kind: Paragraph})
+	for i := 0; i < count; i++ {
+		doc.Append(&Node{Kind: Heading, Level: 2, Text: "Section"})
+	}
+	return doc
+}
+
+func TestOutline(t *testing.T) {
+	got := Outline(headingDoc(3))
+	want := []string{"h-section", "h-section-2", "h-section-3"}
+	if len(got) != len(want) {
+		t.Fatalf("len(Outline()) = %d; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Errorf("Outline()[%d].ID = %q; want %q", i, got[i].ID, want[i])
+		}
+	}
+}
+
+func TestOutlineTemplateIDs(t *testing.T) {
+	doc := &Node{Kind: Document}
+	for _, text := range []string{"Main", "Top", "Suggestions"} {
+		doc.Append(&Node{Kind: Heading, Level: 2, Text: text})
+	}
+	got := Outline(doc)
+	want := []string{"h-main", "h-top", "h-suggestions"}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Errorf("Outline()[%d].ID = %q; want %q", i, got[i].ID, want[i])
+		}
+	}
+}
+
+func TestInsertTOC(t *testing.T) {
+	tests := []struct {
+		name     string
+		headings int
+		limit    int
+		want     int
+	}{
+		{"disabled", 5, 0, -1},
+		{"few", 3, 3, -1},
+		{"many", 4, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := headingDoc(tt.headings)
+			InsertTOC(doc, tt.limit)
+			got := -1
+			for i, n := range doc.Children {
+				if n.Kind == TOC {
+					got = i
+				}
+			}
+			if got != tt.want {
+				t.Errorf("TOC index = %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package ast
+
+import "strings"
+
+type plainWriter struct {
+	buf strings.Builder
+}
+
+func (w *plainWriter) inlines(nodes []*Node) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case Text, InlineMath, Link, Image, InterLink:
+			w.buf.WriteString(n.Text)
+		case WikiLink:
+			w.buf.WriteString(n.Name)
+		case Strong, Emphasis:
+			w.inlines(n.Children)
+		}
+	}
+}
+
+func (w *plainWriter) lines(lines []string) {
+	for _, line := range lines {
+		w.buf.WriteString(line)
+		w.buf.WriteString("\n")
+	}
+}
+
+func (w *plainWriter) list(n *Node) {
+	for _, item := range n.Children {
+		inlines, nested := SplitListItem(item)
+		w.inlines(inlines)
+		w.buf.WriteString("\n")
+		for _, list := range nested {
+			w.list(list)
+		}
+	}
+}
+
+func (w *plainWriter) block(n *Node) {
+	switch n.Kind {
+	case Blank:
+		w.buf.WriteString("\n")
+	case Paragraph:
+		for _, line := range n.Children {
+			w.buf.WriteString(strings.Repeat(" ", line.Indent))
+			w.inlines(line.Children)
+			w.buf.WriteString("\n")
+		}
+	case Heading, Horizon:
+		w.buf.WriteString(n.Text)
+		w.buf.WriteString("\n")
+	case Redirect:
+		w.buf.WriteString(n.Name)
+		w.buf.WriteString("\n")
+	case Raw:
+		w.lines(n.Lines)
+	case Code:
+		w.buf.WriteString("\n")
+		w.lines(n.Lines)
+		w.buf.WriteString("\n")
+	case Math:
+		w.lines(n.Lines)
+		w.buf.WriteString("\n")
+	case List:
+		w.list(n)
+	case Table:
+		for _, row := range n.Children {
+			for i, cell := range row.Children {
+				if i > 0 {
+					w.buf.WriteString("\t")
+				}
+				w.inlines(cell.Children)
+			}
+			w.buf.WriteString("\n")
+		}
+	}
+}
+
+// Plain renders document tree to plain text.
+func Plain(doc *Node) string {
+	w := plainWriter{}
+	for _, n := range doc.Children {
+		w.block(n)
+	}
+	return w.buf.String()
+}
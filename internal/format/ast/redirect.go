@@ -0,0 +1,32 @@
+package ast
+
+import "strings"
+
+// ParseRedirect parses redirect directive line
+// such as "<<redirect PageName>>".
+func ParseRedirect(line string) (string, bool) {
+	if !strings.HasPrefix(line, "<<redirect ") || !strings.HasSuffix(line, ">>") {
+		return "", false
+	}
+	target := strings.TrimSpace(line[len("<<redirect ") : len(line)-len(">>")])
+	if target == "" {
+		return "", false
+	}
+	return target, true
+}
+
+// RedirectOf returns redirect target of document.
+// Document redirects only when directive is its first block
+// except page title and blank lines.
+func RedirectOf(doc *Node) (string, bool) {
+	for _, n := range doc.Children {
+		if n.Kind == Blank || (n.Kind == Heading && n.Title) {
+			continue
+		}
+		if n.Kind == Redirect {
+			return n.Name, true
+		}
+		break
+	}
+	return "", false
+}
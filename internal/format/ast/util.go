@@ -0,0 +1,55 @@
+package ast
+
+import "golang.org/x/text/width"
+
+// TextWidth counts display width of text.
+// East Asian wide characters are counted as two columns.
+func TextWidth(text string) int {
+	n := 0
+	for _, r := range text {
+		kind := width.LookupRune(r).Kind()
+		if kind == width.EastAsianWide || kind == width.EastAsianFullwidth {
+			n += 2
+		} else {
+			n += 1
+		}
+	}
+	return n
+}
+
+// TableColumns counts columns of table node.
+func TableColumns(table *Node) int {
+	columns := len(table.Aligns)
+	for _, row := range table.Children {
+		if len(row.Children) > columns {
+			columns = len(row.Children)
+		}
+	}
+	return columns
+}
+
+// RowCell returns i-th cell of table row.
+// Missing cells of short row are empty cells,
+// which are header cells in header row.
+func RowCell(row *Node, i int) *Node {
+	if i < len(row.Children) {
+		return row.Children[i]
+	}
+	header := len(row.Children) > 0 && row.Children[0].Header
+	return &Node{Kind: TableCell, Header: header}
+}
+
+// SplitListItem splits children of list item
+// into inline nodes and nested lists.
+func SplitListItem(item *Node) ([]*Node, []*Node) {
+	var inlines []*Node
+	var lists []*Node
+	for _, child := range item.Children {
+		if child.Kind == List {
+			lists = append(lists, child)
+		} else {
+			inlines = append(inlines, child)
+		}
+	}
+	return inlines, lists
+}
@@ -0,0 +1,89 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+	"github.com/akikareha/himewiki/internal/format/creole"
+	"github.com/akikareha/himewiki/internal/format/markdown"
+	"github.com/akikareha/himewiki/internal/format/nomark"
+)
+
+// parseAs parses input text as specified format.
+func parseAs(cfg *config.Config, mode string, text string) *ast.Node {
+	if mode == "creole" {
+		return creole.Parse(creole.ToFormatConfig(cfg), text)
+	} else if mode == "markdown" {
+		return markdown.Parse(markdown.ToFormatConfig(cfg), text)
+	} else { // nomark
+		return nomark.Parse(nomark.ToFormatConfig(cfg), text)
+	}
+}
+
+// emitAs writes document tree as specified format.
+func emitAs(mode string, doc *ast.Node) string {
+	if mode == "creole" {
+		return creole.Emit(doc)
+	} else if mode == "markdown" {
+		return markdown.Emit(doc)
+	} else { // nomark
+		return nomark.Emit(doc)
+	}
+}
+
+// comparableHTML renders document tree to HTML
+// without format specific parts.
+func comparableHTML(doc *ast.Node) string {
+	ast.Walk(doc, func(n *ast.Node) bool {
+		// hard line breaks are specific to Nomark
+		n.Break = false
+		return true
+	})
+	return ast.HTML(doc, ast.Style{Markup: false})
+}
+
+// Convert converts input text into specified format.
+// Conversion fails if rendered HTML changes,
+// for example when target format lacks some markup.
+func Convert(cfg *config.Config, title string, text string, to string) (string, error) {
+	if to != "nomark" && to != "creole" && to != "markdown" {
+		return "", fmt.Errorf("unknown format: %s", to)
+	}
+
+	doc := Parse(cfg, text)
+
+	// source markup of one format is meaningless in another
+	ast.Walk(doc, func(n *ast.Node) bool {
+		n.Mark = ""
+		n.EndMark = ""
+		return true
+	})
+
+	// format is detected by title heading at top of text
+	first := (*ast.Node)(nil)
+	if len(doc.Children) > 0 {
+		first = doc.Children[0]
+	}
+	if first == nil || first.Kind != ast.Heading || first.Level != 1 {
+		heading := &ast.Node{Kind: ast.Heading, Level: 1, Text: title, Title: true}
+		blank := &ast.Node{Kind: ast.Blank}
+		if first == nil {
+			doc.Children = []*ast.Node{heading}
+		} else {
+			doc.Children = append([]*ast.Node{heading, blank}, doc.Children...)
+		}
+	}
+
+	converted := emitAs(to, doc)
+	if Detect(cfg, converted) != to {
+		return "", fmt.Errorf("converted text is not detected as %s", to)
+	}
+
+	back := parseAs(cfg, to, converted)
+	if comparableHTML(back) != comparableHTML(doc) {
+		return "", fmt.Errorf("conversion to %s changes page", to)
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		to   string
+		want string
+	}{
+		{
+			"nomark to markdown",
+			"!!!!! Title !!!!!\n\n!!!! Section !!!!\n\nSome **strong** and //em// WikiPage.\n\n- one\n- two\n",
+			"markdown",
+			"# Title\n\n## Section\n\nSome **strong** and *em* WikiPage.\n\n- one\n- two\n",
+		},
+		{
+			"markdown to creole",
+			"# Title\n\nSome __strong__ and _em_ [[page]].\n\n1. one\n2. two\n",
+			"creole",
+			"= Title =\n\nSome **strong** and //em// [[page]].\n\n# one\n# two\n",
+		},
+		{
+			"creole to nomark",
+			"= Title =\n\n{{{\ncode\n}}}\n",
+			"nomark",
+			"!!!!! Title !!!!!\n\n{{{\n\ncode\n\n}}}\n\n",
+		},
+		{
+			"untitled",
+			"Some text.\n",
+			"creole",
+			"= Untitled =\n\nSome text.\n",
+		},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(cfg, "Untitled", tt.text, tt.to)
+			if err != nil {
+				t.Fatalf("Convert(%q) failed: %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("Convert(%q) = %q; want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertLossy(t *testing.T) {
+	cfg := &config.Config{}
+	tests := []struct {
+		name string
+		text string
+		to   string
+	}{
+		{"unknown format", "= Title =\n", "html"},
+		{"alignments", "# Title\n\n| a | b |\n| :-- | --: |\n| c | d |\n", "creole"},
+		{"deep heading", "# Title\n\n#### Deep\n", "nomark"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Convert(cfg, "Untitled", tt.text, tt.to)
+			if err == nil {
+				t.Errorf("Convert(%q) succeeded; want error", tt.text)
+			}
+		})
+	}
+}
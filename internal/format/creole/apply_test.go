@@ -89,7 +89,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"== Test ==\n",
 			"Test\n",
-			"<h2>Test</h2>\n",
+			"<h2 id=\"h-test\">Test</h2>\n",
 		},
 		{
 			"heading 3",
@@ -98,7 +98,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"=== Test ===\n",
 			"Test\n",
-			"<h3>Test</h3>\n",
+			"<h3 id=\"h-test\">Test</h3>\n",
 		},
 		{
 			"heading 4",
@@ -107,7 +107,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"==== Test ====\n",
 			"Test\n",
-			"<h4>Test</h4>\n",
+			"<h4 id=\"h-test\">Test</h4>\n",
 		},
 		{
 			"heading 5",
@@ -116,7 +116,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"===== Test =====\n",
 			"Test\n",
-			"<h5>Test</h5>\n",
+			"<h5 id=\"h-test\">Test</h5>\n",
 		},
 		{
 			"heading 6",
@@ -125,7 +125,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"====== Test ======\n",
 			"Test\n",
-			"<h6>Test</h6>\n",
+			"<h6 id=\"h-test\">Test</h6>\n",
 		},
 		{
 			"strong",
@@ -154,6 +154,15 @@ func TestApply(t *testing.T) {
 			"See also Test page.\n",
 			"<p>\nSee also <a href=\"/Test\" class=\"link\">Test</a> page.\n</p>\n",
 		},
+		{
+			"redirect",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"Test\n",
+			"<p class=\"redirect\">Redirect to <a href=\"/Test\" class=\"link\">Test</a></p>\n",
+		},
 		{
 			"code",
 			"WikiPage",
@@ -181,6 +190,69 @@ func TestApply(t *testing.T) {
 			"Mass is energy:\nE=mc^2\n\n",
 			"<p>\nMass is energy:\n</p>\n<div>\n<nomark-math class=\"mathjax\">\\[E=mc^2\n\\]</nomark-math>\n</div>\n",
 		},
+		{
+			"list",
+			"WikiPage",
+			"* apple\n* banana\n",
+			"WikiPage",
+			"* apple\n* banana\n",
+			"apple\nbanana\n",
+			"<ul>\n<li>apple</li>\n<li>banana</li>\n</ul>\n",
+		},
+		{
+			"ordered list",
+			"WikiPage",
+			"Steps:\n# open\n # close\n",
+			"WikiPage",
+			"Steps:\n# open\n# close\n",
+			"Steps:\nopen\nclose\n",
+			"<p>\nSteps:\n</p>\n<ol>\n<li>open</li>\n<li>close</li>\n</ol>\n",
+		},
+		{
+			"nested list",
+			"WikiPage",
+			"# fruits\n** apple\n# **nuts**\n\n**Done.**\n",
+			"WikiPage",
+			"# fruits\n** apple\n# **nuts**\n\n**Done.**\n",
+			"fruits\napple\nnuts\n\nDone.\n",
+			"<ol>\n<li>fruits\n<ul>\n<li>apple</li>\n</ul>\n</li>\n<li><strong>nuts</strong></li>\n</ol>\n<p>\n<strong>Done.</strong>\n</p>\n",
+		},
+		{
+			"uneven nested list",
+			"WikiPage",
+			"* a\n*** b\n** c\n* d\n",
+			"WikiPage",
+			"* a\n** b\n** c\n* d\n",
+			"a\nb\nc\nd\n",
+			"<ul>\n<li>a\n<ul>\n<li>b</li>\n<li>c</li>\n</ul>\n</li>\n<li>d</li>\n</ul>\n",
+		},
+		{
+			"table",
+			"WikiPage",
+			"|=名前|=Age|\n|**Aki**|17|\n|Bob\n",
+			"WikiPage",
+			"|= 名前    |= Age |\n|  **Aki** |  17  |\n|  Bob     |      |\n",
+			"名前\tAge\nAki\t17\nBob\n",
+			"<table>\n<tr>\n<th>名前</th>\n<th>Age</th>\n</tr>\n<tr>\n<td><strong>Aki</strong></td>\n<td>17</td>\n</tr>\n<tr>\n<td>Bob</td>\n<td></td>\n</tr>\n</table>\n",
+		},
+		{
+			"short header row",
+			"WikiPage",
+			"|= a |\n| 1 | 2 | 3 |\n",
+			"WikiPage",
+			"|= a |=   |=   |\n|  1 |  2 |  3 |\n",
+			"a\n1\t2\t3\n",
+			"<table>\n<tr>\n<th>a</th>\n<th></th>\n<th></th>\n</tr>\n<tr>\n<td>1</td>\n<td>2</td>\n<td>3</td>\n</tr>\n</table>\n",
+		},
+		{
+			"table wikilink with bar",
+			"WikiPage",
+			"|= [[Page|label]] |= b |\n| c | d |\n",
+			"WikiPage",
+			"|= [[Page|label]] |= b |\n|  c              |  d |\n",
+			"Page|label\tb\nc\td\n",
+			"<table>\n<tr>\n<th><a href=\"/Page%7Clabel\" class=\"link\">Page|label</a></th>\n<th>b</th>\n</tr>\n<tr>\n<td>c</td>\n<td>d</td>\n</tr>\n</table>\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -12,6 +12,7 @@ type imageConfig struct {
 type formatConfig struct {
 	image imageConfig
 	links []config.Link
+	toc   int
 }
 
 func ToFormatConfig(cfg *config.Config) formatConfig {
@@ -19,5 +20,6 @@ func ToFormatConfig(cfg *config.Config) formatConfig {
 	fc.image.domains = cfg.Image.Domains
 	fc.image.extensions = cfg.Image.Extensions
 	fc.links = cfg.Links
+	fc.toc = cfg.Wiki.TOC
 	return fc
 }
@@ -0,0 +1,181 @@
+package creole
+
+import (
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+type emitter struct {
+	buf strings.Builder
+}
+
+func emitInlines(buf *strings.Builder, nodes []*ast.Node) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case ast.Text, ast.Link, ast.Image, ast.InterLink:
+			buf.WriteString(n.Text)
+		case ast.Strong:
+			buf.WriteString("**")
+			emitInlines(buf, n.Children)
+			if n.Closed {
+				buf.WriteString("**")
+			}
+		case ast.Emphasis:
+			buf.WriteString("//")
+			emitInlines(buf, n.Children)
+			if n.Closed {
+				buf.WriteString("//")
+			}
+		case ast.InlineMath:
+			buf.WriteString("%%")
+			buf.WriteString(n.Text)
+			buf.WriteString("%%")
+		case ast.WikiLink:
+			if isCamel(n.Name) {
+				buf.WriteString(n.Name)
+			} else {
+				buf.WriteString("[[")
+				buf.WriteString(n.Name)
+				buf.WriteString("]]")
+			}
+		}
+	}
+}
+
+// isCamel tells name is written as CamelCase link.
+func isCamel(name string) bool {
+	s := state{input: name, lineEnd: len(name), doc: &ast.Node{}}
+	s.inline = s.doc
+	return camel(&s) && s.index == len(name)
+}
+
+func (e *emitter) inlineText(nodes []*ast.Node) string {
+	var buf strings.Builder
+	emitInlines(&buf, nodes)
+	return buf.String()
+}
+
+func (e *emitter) lines(lines []string) {
+	for _, line := range lines {
+		e.buf.WriteString(line)
+		e.buf.WriteString("\n")
+	}
+}
+
+func (e *emitter) list(n *ast.Node, depth int) {
+	mark := "*"
+	if n.Ordered {
+		mark = "#"
+	}
+	for _, item := range n.Children {
+		e.buf.WriteString(strings.Repeat(mark, depth))
+		e.buf.WriteString(" ")
+		inlines, nested := ast.SplitListItem(item)
+		e.buf.WriteString(e.inlineText(inlines))
+		e.buf.WriteString("\n")
+		for _, list := range nested {
+			e.list(list, depth+1)
+		}
+	}
+}
+
+func tableCellSource(cell *ast.Node, text string) string {
+	if cell.Header {
+		return "= " + text
+	}
+	return "  " + text
+}
+
+func (e *emitter) table(n *ast.Node) {
+	columns := ast.TableColumns(n)
+	sources := make([][]string, len(n.Children))
+	widths := make([]int, columns)
+	for r, row := range n.Children {
+		for i, cell := range row.Children {
+			source := tableCellSource(cell, e.inlineText(cell.Children))
+			sources[r] = append(sources[r], source)
+			w := ast.TextWidth(source)
+			if w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	for r, row := range sources {
+		e.buf.WriteString("|")
+		for i := 0; i < columns; i++ {
+			var source string
+			if i < len(row) {
+				source = row[i]
+			} else {
+				source = tableCellSource(ast.RowCell(n.Children[r], i), "")
+			}
+			e.buf.WriteString(source)
+			e.buf.WriteString(strings.Repeat(" ", widths[i]-ast.TextWidth(source)))
+			e.buf.WriteString(" |")
+		}
+		e.buf.WriteString("\n")
+	}
+}
+
+func (e *emitter) block(n *ast.Node) {
+	switch n.Kind {
+	case ast.Blank:
+		e.buf.WriteString("\n")
+	case ast.Paragraph:
+		for _, line := range n.Children {
+			e.buf.WriteString(strings.Repeat(" ", line.Indent))
+			e.buf.WriteString(e.inlineText(line.Children))
+			e.buf.WriteString("\n")
+		}
+	case ast.Heading:
+		level := n.Level
+		if level > headingMaxLevel {
+			level = headingMaxLevel
+		}
+		mark := headingMark(level)
+		e.buf.WriteString(mark)
+		e.buf.WriteString(" ")
+		e.buf.WriteString(n.Text)
+		e.buf.WriteString(" ")
+		e.buf.WriteString(mark)
+		e.buf.WriteString("\n")
+	case ast.Horizon:
+		if strings.HasPrefix(n.Text, "-") && strings.HasSuffix(n.Text, "-") {
+			e.buf.WriteString(n.Text)
+		} else {
+			e.buf.WriteString("----")
+		}
+		e.buf.WriteString("\n")
+	case ast.Raw:
+		e.lines(n.Lines)
+	case ast.Code:
+		e.buf.WriteString("{{{\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("}}}\n")
+	case ast.Math:
+		e.buf.WriteString("%%%\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("%%%\n\n")
+	case ast.List:
+		e.list(n, 1)
+	case ast.Table:
+		e.table(n)
+	case ast.TOC:
+		e.buf.WriteString("<<toc>>\n\n")
+	case ast.Redirect:
+		e.buf.WriteString("<<redirect ")
+		e.buf.WriteString(n.Name)
+		e.buf.WriteString(">>\n")
+	}
+}
+
+// Emit writes document tree as Creole text.
+func Emit(doc *ast.Node) string {
+	e := emitter{}
+	for _, n := range doc.Children {
+		e.block(n)
+	}
+	return e.buf.String()
+}
@@ -0,0 +1,23 @@
+package creole
+
+import "testing"
+
+func TestEmitStable(t *testing.T) {
+	tests := []string{
+		"= Title =\n\nThis is **strong** and //em//.\nWikiPage and [[page]].\n",
+		"* one\n* two\n** three\n## four\n",
+		"|= a |= b |\n| c | d |\n",
+		"{{{\ncode\n}}}\n\n%%%\nx^2\n%%%\n",
+		"text\n\n raw\n\n----\n",
+		"|= a |\n| 1 | 2 | 3 |\n",
+		"|= [[Page|label]] |= b |\n| c | d |\n",
+	}
+
+	for _, text := range tests {
+		first := Emit(Parse(mockCfg, text))
+		second := Emit(Parse(mockCfg, first))
+		if second != first {
+			t.Errorf("Emit(Parse(%q)) = %q, want %q", first, second, first)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package format
 
 import (
 	"html/template"
+	"strconv"
 	"strings"
+
+	"github.com/akikareha/himewiki/internal/util"
 )
 
 func escapeIndentHTML(line string) string {
@@ -16,7 +19,71 @@ func escapeIndentHTML(line string) string {
 	return buf.String()
 }
 
+// escapeSpansHTML is same as escapeIndentHTML
+// but also marks changed runs of line.
+func escapeSpansHTML(spans []util.Span) string {
+	var buf strings.Builder
+	indent := true
+	for _, span := range spans {
+		text := span.Text
+		if span.Changed {
+			buf.WriteString("<span class=\"changed\">")
+		}
+		if indent {
+			trimmed := strings.TrimLeft(text, " ")
+			buf.WriteString(strings.Repeat("&nbsp;", len(text)-len(trimmed)))
+			text = trimmed
+			indent = text == ""
+		}
+		buf.WriteString(template.HTMLEscapeString(text))
+		if span.Changed {
+			buf.WriteString("</span>")
+		}
+	}
+	return buf.String()
+}
+
+func writeMinusLine(html *strings.Builder, content string) {
+	html.WriteString("<em class=\"minus\">-</em>")
+	html.WriteString("<em class=\"minus-line\">")
+	html.WriteString(content)
+	html.WriteString("</em><br />\n")
+}
+
+func writePlusLine(html *strings.Builder, content string) {
+	html.WriteString("<strong class=\"plus\">+</strong>")
+	html.WriteString("<strong class=\"plus-line\">")
+	html.WriteString(content)
+	html.WriteString("</strong><br />\n")
+}
+
+// writeChanges writes removed lines and added lines
+// highlighting changed words of paired lines.
+func writeChanges(html *strings.Builder, removed []string, added []string) {
+	oldHTML := make([]string, len(removed))
+	newHTML := make([]string, len(added))
+	for i := range removed {
+		oldHTML[i] = escapeIndentHTML(removed[i])
+	}
+	for i := range added {
+		newHTML[i] = escapeIndentHTML(added[i])
+	}
+	for i := 0; i < len(removed) && i < len(added); i++ {
+		oldSpans, newSpans := util.InlineDiff(removed[i], added[i])
+		oldHTML[i] = escapeSpansHTML(oldSpans)
+		newHTML[i] = escapeSpansHTML(newSpans)
+	}
+
+	for _, line := range oldHTML {
+		writeMinusLine(html, line)
+	}
+	for _, line := range newHTML {
+		writePlusLine(html, line)
+	}
+}
+
 // Diff formats diff text to HTML.
+// Changed words are highlighted in pairs of removed and added lines.
 func Diff(text string) string {
 	index := 0
 
@@ -27,28 +94,36 @@ func Diff(text string) string {
 		lineNumber++
 	}
 
-	var html strings.Builder
+	var lines []string
 	for index < len(text) {
 		lineEnd, nextLine := indexLineEnd(text, index)
-		line := text[index:lineEnd]
+		lines = append(lines, text[index:lineEnd])
 		index = nextLine
+	}
+
+	var html strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
 
 		if len(line) < 1 {
 			html.WriteString("<br />\n")
+			i++
 			continue
 		}
 
 		c := line[0]
-		if c == '+' {
-			html.WriteString("<strong class=\"plus\">+</strong>")
-			html.WriteString("<strong class=\"plus-line\">")
-			html.WriteString(escapeIndentHTML(line[1:]))
-			html.WriteString("</strong><br />\n")
-		} else if c == '-' {
-			html.WriteString("<em class=\"minus\">-</em>")
-			html.WriteString("<em class=\"minus-line\">")
-			html.WriteString(escapeIndentHTML(line[1:]))
-			html.WriteString("</em><br />\n")
+		if c == '+' || c == '-' {
+			var removed, added []string
+			for i < len(lines) && strings.HasPrefix(lines[i], "-") {
+				removed = append(removed, lines[i][1:])
+				i++
+			}
+			for i < len(lines) && strings.HasPrefix(lines[i], "+") {
+				added = append(added, lines[i][1:])
+				i++
+			}
+			writeChanges(&html, removed, added)
+			continue
 		} else if c == '@' {
 			html.WriteString("<span class=\"hunk\">")
 			html.WriteString(template.HTMLEscapeString(line))
@@ -60,7 +135,53 @@ func Diff(text string) string {
 			html.WriteString(template.HTMLEscapeString(line))
 			html.WriteString("<br />\n")
 		}
+		i++
+	}
+
+	return html.String()
+}
+
+func writeSideCell(html *strings.Builder, no int, content string, class string) {
+	if no == 0 {
+		html.WriteString("<td class=\"line-no\"></td><td></td>")
+		return
+	}
+	html.WriteString("<td class=\"line-no\">")
+	html.WriteString(strconv.Itoa(no))
+	html.WriteString("</td><td")
+	if class != "" {
+		html.WriteString(" class=\"")
+		html.WriteString(class)
+		html.WriteString("\"")
 	}
+	html.WriteString(">")
+	html.WriteString(content)
+	html.WriteString("</td>")
+}
 
+// SideBySide formats rows of side-by-side diff to HTML table.
+func SideBySide(rows []util.DiffRow) string {
+	var html strings.Builder
+	html.WriteString("<table class=\"side-diff\">\n")
+	for _, row := range rows {
+		if row.Op == '@' {
+			html.WriteString("<tr class=\"hunk\"><td colspan=\"4\">...</td></tr>\n")
+			continue
+		}
+		oldClass, newClass := "", ""
+		if row.Op != ' ' {
+			oldClass, newClass = "minus-line", "plus-line"
+		}
+		oldHTML, newHTML := escapeIndentHTML(row.Old), escapeIndentHTML(row.New)
+		if row.Op == '~' {
+			oldSpans, newSpans := util.InlineDiff(row.Old, row.New)
+			oldHTML, newHTML = escapeSpansHTML(oldSpans), escapeSpansHTML(newSpans)
+		}
+		html.WriteString("<tr>")
+		writeSideCell(&html, row.OldNo, oldHTML, oldClass)
+		writeSideCell(&html, row.NewNo, newHTML, newClass)
+		html.WriteString("</tr>\n")
+	}
+	html.WriteString("</table>\n")
 	return html.String()
 }
@@ -1,6 +1,10 @@
 package format
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/util"
+)
 
 func TestDiff(t *testing.T) {
 	tests := []struct {
@@ -16,6 +20,25 @@ func TestDiff(t *testing.T) {
 		{"plus indent", "--- old\r\n+++ new\r\n+  test\r\n", "<strong class=\"plus\">+</strong><strong class=\"plus-line\">&nbsp;&nbsp;test</strong><br />\n"},
 		{"minus indent", "--- old\r\n+++ new\r\n-  test\r\n", "<em class=\"minus\">-</em><em class=\"minus-line\">&nbsp;&nbsp;test</em><br />\n"},
 		{"text indent", "--- old\r\n+++ new\r\n   test\r\n", "&nbsp;&nbsp;&nbsp;test<br />\n"},
+		{
+			"changed word",
+			"--- old\r\n+++ new\r\n-  a big cat\r\n+  a small cat\r\n",
+			"<em class=\"minus\">-</em><em class=\"minus-line\">&nbsp;&nbsp;a <span class=\"changed\">big</span> cat</em><br />\n" +
+				"<strong class=\"plus\">+</strong><strong class=\"plus-line\">&nbsp;&nbsp;a <span class=\"changed\">small</span> cat</strong><br />\n",
+		},
+		{
+			"changed japanese",
+			"--- old\r\n+++ new\r\n-猫が好きです。\r\n+犬が好きです。\r\n",
+			"<em class=\"minus\">-</em><em class=\"minus-line\"><span class=\"changed\">猫</span>が好きです。</em><br />\n" +
+				"<strong class=\"plus\">+</strong><strong class=\"plus-line\"><span class=\"changed\">犬</span>が好きです。</strong><br />\n",
+		},
+		{
+			"unpaired",
+			"--- old\r\n+++ new\r\n-a b\r\n-c\r\n+a x\r\n",
+			"<em class=\"minus\">-</em><em class=\"minus-line\">a <span class=\"changed\">b</span></em><br />\n" +
+				"<em class=\"minus\">-</em><em class=\"minus-line\">c</em><br />\n" +
+				"<strong class=\"plus\">+</strong><strong class=\"plus-line\">a <span class=\"changed\">x</span></strong><br />\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -27,3 +50,43 @@ func TestDiff(t *testing.T) {
 		})
 	}
 }
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []util.DiffRow
+		want string
+	}{
+		{"zero", nil, "<table class=\"side-diff\">\n</table>\n"},
+		{
+			"changed",
+			[]util.DiffRow{{Op: '~', OldNo: 1, Old: "  a < b", NewNo: 1, New: "  a > b"}},
+			"<table class=\"side-diff\">\n" +
+				"<tr><td class=\"line-no\">1</td><td class=\"minus-line\">&nbsp;&nbsp;a <span class=\"changed\">&lt;</span> b</td>" +
+				"<td class=\"line-no\">1</td><td class=\"plus-line\">&nbsp;&nbsp;a <span class=\"changed\">&gt;</span> b</td></tr>\n" +
+				"</table>\n",
+		},
+		{
+			"added",
+			[]util.DiffRow{{Op: '+', NewNo: 2, New: "new"}},
+			"<table class=\"side-diff\">\n" +
+				"<tr><td class=\"line-no\"></td><td></td>" +
+				"<td class=\"line-no\">2</td><td class=\"plus-line\">new</td></tr>\n" +
+				"</table>\n",
+		},
+		{
+			"hunk",
+			[]util.DiffRow{{Op: '@'}},
+			"<table class=\"side-diff\">\n<tr class=\"hunk\"><td colspan=\"4\">...</td></tr>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SideBySide(tt.rows)
+			if got != tt.want {
+				t.Errorf("SideBySide(%v) = %s; want %s", tt.rows, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package format
+
+import (
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+// Links lists outgoing wiki links and interwiki links of text.
+func Links(cfg *config.Config, text string) []ast.LinkTarget {
+	return ast.Links(Parse(cfg, text))
+}
+
+// RenameLinks rewrites wiki links and redirect to page from
+// into ones to page to.
+// Links are written as CamelCase or bracketed as the format allows.
+// Text is returned as is when it has no such links.
+func RenameLinks(cfg *config.Config, text string, from string, to string) (string, bool) {
+	mode := Detect(cfg, text)
+	doc := parseAs(cfg, mode, text)
+	changed := false
+	ast.Walk(doc, func(n *ast.Node) bool {
+		if (n.Kind == ast.WikiLink || n.Kind == ast.Redirect) && n.Name == from {
+			n.Name = to
+			changed = true
+		}
+		return true
+	})
+	if !changed {
+		return text, false
+	}
+	return emitAs(mode, doc), true
+}
+
+// Redirect returns target page of redirect directive of text.
+func Redirect(cfg *config.Config, text string) (string, bool) {
+	return ast.RedirectOf(Parse(cfg, text))
+}
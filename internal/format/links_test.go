@@ -0,0 +1,148 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []ast.LinkTarget
+	}{
+		{
+			"nomark",
+			"!!!!! Title !!!!!\n\nSee WikiName and [[other page]].\n\nAgain WikiName and wp:Go\n",
+			[]ast.LinkTarget{
+				{Name: "WikiName"},
+				{Name: "other page"},
+				{Name: "wp:Go", Inter: true},
+			},
+		},
+		{
+			"creole",
+			"= Title =\n\nSee [[FrontPage]] and WikiName.\n",
+			[]ast.LinkTarget{
+				{Name: "FrontPage"},
+				{Name: "WikiName"},
+			},
+		},
+		{
+			"markdown",
+			"# Title\n\nSee [[FrontPage]].\n",
+			[]ast.LinkTarget{
+				{Name: "FrontPage"},
+			},
+		},
+		{
+			"none",
+			"!!!!! Title !!!!!\n\nplain text\n",
+			nil,
+		},
+	}
+
+	cfg := &config.Config{}
+	cfg.Links = []config.Link{{Key: "wp", URL: "https://en.wikipedia.org/wiki/"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Links(cfg, tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Links(%q) = %v; want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		from        string
+		to          string
+		want        string
+		wantChanged bool
+	}{
+		{
+			"camel to camel",
+			"!!!!! Title !!!!!\n\nSee OldName and [[OldName]].\n",
+			"OldName",
+			"NewName",
+			"!!!!! Title !!!!!\n\nSee NewName and [[NewName]].\n",
+			true,
+		},
+		{
+			"camel to bracket",
+			"= Title =\n\nSee OldName.\n",
+			"OldName",
+			"new name",
+			"= Title =\n\nSee [[new name]].\n",
+			true,
+		},
+		{
+			"markdown",
+			"# Title\n\nSee [[old name]] and [[other]].\n",
+			"old name",
+			"NewName",
+			"# Title\n\nSee NewName and [[other]].\n",
+			true,
+		},
+		{
+			"redirect",
+			"<<redirect OldName>>\n",
+			"OldName",
+			"NewName",
+			"<<redirect NewName>>\n",
+			true,
+		},
+		{
+			"unchanged",
+			"!!!!! Title !!!!!\n\nSee OtherName.\n",
+			"OldName",
+			"NewName",
+			"!!!!! Title !!!!!\n\nSee OtherName.\n",
+			false,
+		},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := RenameLinks(cfg, tt.text, tt.from, tt.to)
+			if got != tt.want || changed != tt.wantChanged {
+				t.Errorf("RenameLinks(%q, %q, %q) = %q, %v; want %q, %v",
+					tt.text, tt.from, tt.to, got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		wantOk bool
+	}{
+		{"nomark", "!!!!! K8s !!!!!\n\n<<redirect Kubernetes>>\n", "Kubernetes", true},
+		{"creole", "= K8s =\n\n<<redirect Kubernetes>>\n", "Kubernetes", true},
+		{"markdown", "# K8s\n\n<<redirect Kubernetes>>\n", "Kubernetes", true},
+		{"no title", "<<redirect Container Orchestration>>\n", "Container Orchestration", true},
+		{"not first", "!!!!! K8s !!!!!\n\nSee below.\n\n<<redirect Kubernetes>>\n", "", false},
+		{"in code", "{{{\n<<redirect Kubernetes>>\n}}}\n", "", false},
+		{"empty", "<<redirect >>\n", "", false},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Redirect(cfg, tt.text)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Redirect(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
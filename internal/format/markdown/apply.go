@@ -1,13 +1,13 @@
 package markdown
 
 import (
-	"html/template"
 	"net/url"
 	"path"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/akikareha/himewiki/internal/format/ast"
 )
 
 type blockMode int
@@ -19,6 +19,8 @@ const (
 	blockRaw
 	blockCode
 	blockMath
+	blockList
+	blockTable
 )
 
 type decoMode int
@@ -29,13 +31,18 @@ const (
 	decoEm
 )
 
+type listLevel struct {
+	node   *ast.Node
+	indent int
+}
+
 type state struct {
 	config    formatConfig
 	input     string
 	index     int
-	text      *strings.Builder
-	plain     *strings.Builder
-	html      *strings.Builder
+	doc       *ast.Node
+	node      *ast.Node
+	inline    *ast.Node
 	block     blockMode
 	nextLine  int
 	lineEnd   int
@@ -44,22 +51,40 @@ type state struct {
 	title     string
 	outerDeco decoMode
 	innerDeco decoMode
+	outer     *ast.Node
+	inner     *ast.Node
+	lists     []listLevel
+
+	// cell tells inline text is in table cell.
+	cell bool
 }
 
-func closeDecos(s *state) {
-	if s.innerDeco == decoStrong {
-		s.html.WriteString("</strong>")
-	} else if s.innerDeco == decoEm {
-		s.html.WriteString("</em>")
+// appendInline appends inline node to innermost decoration.
+func appendInline(s *state, n *ast.Node) *ast.Node {
+	if s.inner != nil {
+		return s.inner.Append(n)
+	} else if s.outer != nil {
+		return s.outer.Append(n)
 	}
-	s.innerDeco = decoNone
+	return s.inline.Append(n)
+}
 
-	if s.outerDeco == decoStrong {
-		s.html.WriteString("</strong>")
-	} else if s.outerDeco == decoEm {
-		s.html.WriteString("</em>")
+// appendText appends text to innermost decoration.
+func appendText(s *state, text string) {
+	if s.inner != nil {
+		s.inner.AppendText(text)
+	} else if s.outer != nil {
+		s.outer.AppendText(text)
+	} else {
+		s.inline.AppendText(text)
 	}
+}
+
+func closeDecos(s *state) {
+	s.innerDeco = decoNone
+	s.inner = nil
 	s.outerDeco = decoNone
+	s.outer = nil
 }
 
 func skipBlankLines(s *state) {
@@ -90,30 +115,31 @@ func skipBlankLines(s *state) {
 func closeBlock(s *state, nextBlock blockMode) {
 	if s.block == blockParagraph {
 		closeDecos(s)
-		s.html.WriteString("</p>\n")
 		if nextBlock != blockRaw && nextBlock != blockCode {
 			skipBlankLines(s)
 		}
 	} else if s.block == blockHorizon {
 		skipBlankLines(s)
 	} else if s.block == blockRaw {
-		s.html.WriteString("</code></pre>\n")
 		skipBlankLines(s)
 	} else if s.block == blockCode {
-		s.html.WriteString("</code></pre>\n")
 		skipBlankLines(s)
 	} else if s.block == blockMath {
-		s.text.WriteString("%%%\n\n")
-
-		s.plain.WriteString("\n")
-
-		s.html.WriteString("\\]</nomark-math>\n")
-		s.html.WriteString("</div>\n")
-
 		skipBlankLines(s)
+	} else if s.block == blockList {
+		s.lists = nil
+		if nextBlock != blockRaw && nextBlock != blockCode {
+			skipBlankLines(s)
+		}
+	} else if s.block == blockTable {
+		closeTable(s)
+		if nextBlock != blockRaw && nextBlock != blockCode {
+			skipBlankLines(s)
+		}
 	}
 
 	s.block = blockNone
+	s.node = nil
 }
 
 func openBlock(s *state, nextBlock blockMode) {
@@ -122,18 +148,17 @@ func openBlock(s *state, nextBlock blockMode) {
 	}
 
 	if nextBlock == blockParagraph {
-		s.html.WriteString("<p>\n")
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Paragraph})
 	} else if nextBlock == blockHorizon {
-		// do nothing
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Horizon})
 	} else if nextBlock == blockRaw {
-		s.html.WriteString("<pre><code>")
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Raw})
 	} else if nextBlock == blockCode {
-		s.html.WriteString("<pre><code>")
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Code})
 	} else if nextBlock == blockMath {
-		s.text.WriteString("%%%\n")
-
-		s.html.WriteString("<div>\n")
-		s.html.WriteString("<nomark-math class=\"mathjax\">\\[")
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Math})
+	} else if nextBlock == blockTable {
+		s.node = s.doc.Append(&ast.Node{Kind: ast.Table})
 	}
 
 	s.block = nextBlock
@@ -157,55 +182,49 @@ func math(s *state) bool {
 		return false
 	}
 	text := s.input[s.index+2 : s.index+2+end]
-	html := template.HTMLEscapeString(text)
-
-	s.text.WriteString("%%")
-	s.text.WriteString(text)
-	s.text.WriteString("%%")
-
-	s.plain.WriteString(text)
 
-	s.html.WriteString("<nomark-math class=\"mathjax\">\\(")
-	s.html.WriteString(html)
-	s.html.WriteString("\\)</nomark-math>")
+	appendInline(s, &ast.Node{Kind: ast.InlineMath, Text: text})
 
 	s.index += 2 + end + 2
 	return true
 }
 
-func strong(s *state) bool {
-	line := s.input[s.index:s.lineEnd]
-	if !strings.HasPrefix(line, "**") {
-		return false
-	}
-
-	if s.innerDeco == decoStrong {
-		s.text.WriteString("**")
-		s.html.WriteString("</strong>")
+// deco opens or closes decoration of specified mode.
+// Source markup is kept as mark to be written back as is.
+func deco(s *state, mode decoMode, kind ast.Kind, mark string) {
+	if s.innerDeco == mode {
+		s.inner.Closed = true
 		s.innerDeco = decoNone
-		s.index += 2
-		return true
+		s.inner = nil
+		return
 	}
 
-	if s.outerDeco == decoStrong {
-		if s.innerDeco == decoEm {
-			s.html.WriteString("</em>")
-			s.innerDeco = decoNone
-		}
-		s.text.WriteString("**")
-		s.html.WriteString("</strong>")
+	if s.outerDeco == mode {
+		// inner decoration is implicitly closed
+		s.innerDeco = decoNone
+		s.inner = nil
+		s.outer.Closed = true
 		s.outerDeco = decoNone
-		s.index += 2
-		return true
+		s.outer = nil
+		return
 	}
 
-	s.text.WriteString("**")
-	s.html.WriteString("<strong>")
+	n := appendInline(s, &ast.Node{Kind: kind, Mark: mark})
 	if s.outerDeco == decoNone {
-		s.outerDeco = decoStrong
+		s.outerDeco = mode
+		s.outer = n
 	} else {
-		s.innerDeco = decoStrong
+		s.innerDeco = mode
+		s.inner = n
 	}
+}
+
+func strong(s *state) bool {
+	line := s.input[s.index:s.lineEnd]
+	if !strings.HasPrefix(line, "**") {
+		return false
+	}
+	deco(s, decoStrong, ast.Strong, "**")
 	s.index += 2
 	return true
 }
@@ -215,34 +234,7 @@ func altStrong(s *state) bool {
 	if !strings.HasPrefix(line, "__") {
 		return false
 	}
-
-	if s.innerDeco == decoStrong {
-		s.text.WriteString("__")
-		s.html.WriteString("</strong>")
-		s.innerDeco = decoNone
-		s.index += 2
-		return true
-	}
-
-	if s.outerDeco == decoStrong {
-		if s.innerDeco == decoEm {
-			s.html.WriteString("</em>")
-			s.innerDeco = decoNone
-		}
-		s.text.WriteString("__")
-		s.html.WriteString("</strong>")
-		s.outerDeco = decoNone
-		s.index += 2
-		return true
-	}
-
-	s.text.WriteString("__")
-	s.html.WriteString("<strong>")
-	if s.outerDeco == decoNone {
-		s.outerDeco = decoStrong
-	} else {
-		s.innerDeco = decoStrong
-	}
+	deco(s, decoStrong, ast.Strong, "__")
 	s.index += 2
 	return true
 }
@@ -252,34 +244,7 @@ func em(s *state) bool {
 	if !strings.HasPrefix(line, "*") {
 		return false
 	}
-
-	if s.innerDeco == decoEm {
-		s.text.WriteString("*")
-		s.html.WriteString("</em>")
-		s.innerDeco = decoNone
-		s.index += 1
-		return true
-	}
-
-	if s.outerDeco == decoEm {
-		if s.innerDeco == decoStrong {
-			s.html.WriteString("</strong>")
-			s.innerDeco = decoNone
-		}
-		s.text.WriteString("*")
-		s.html.WriteString("</em>")
-		s.outerDeco = decoNone
-		s.index += 1
-		return true
-	}
-
-	s.text.WriteString("*")
-	s.html.WriteString("<em>")
-	if s.outerDeco == decoNone {
-		s.outerDeco = decoEm
-	} else {
-		s.innerDeco = decoEm
-	}
+	deco(s, decoEm, ast.Emphasis, "*")
 	s.index += 1
 	return true
 }
@@ -289,34 +254,7 @@ func altEm(s *state) bool {
 	if !strings.HasPrefix(line, "_") {
 		return false
 	}
-
-	if s.innerDeco == decoEm {
-		s.text.WriteString("_")
-		s.html.WriteString("</em>")
-		s.innerDeco = decoNone
-		s.index += 1
-		return true
-	}
-
-	if s.outerDeco == decoEm {
-		if s.innerDeco == decoStrong {
-			s.html.WriteString("</strong>")
-			s.innerDeco = decoNone
-		}
-		s.text.WriteString("_")
-		s.html.WriteString("</em>")
-		s.outerDeco = decoNone
-		s.index += 1
-		return true
-	}
-
-	s.text.WriteString("_")
-	s.html.WriteString("<em>")
-	if s.outerDeco == decoNone {
-		s.outerDeco = decoEm
-	} else {
-		s.innerDeco = decoEm
-	}
+	deco(s, decoEm, ast.Emphasis, "_")
 	s.index += 1
 	return true
 }
@@ -401,15 +339,7 @@ func camel(s *state) bool {
 	}
 	name := line[:i]
 
-	s.text.WriteString(name)
-
-	s.plain.WriteString(name)
-
-	s.html.WriteString("<a href=\"/")
-	s.html.WriteString(url.PathEscape(name))
-	s.html.WriteString("\" class=\"link\">")
-	s.html.WriteString(template.HTMLEscapeString(name))
-	s.html.WriteString("</a>")
+	appendInline(s, &ast.Node{Kind: ast.WikiLink, Name: name})
 
 	s.index += len(name)
 	return true
@@ -426,20 +356,7 @@ func wikiLink(s *state) bool {
 	}
 	name := line[2 : 2+ket]
 
-	s.text.WriteString("[[")
-	s.text.WriteString(name)
-	s.text.WriteString("]]")
-
-	s.plain.WriteString(name)
-
-	s.html.WriteString("<a href=\"/")
-	s.html.WriteString(url.PathEscape(name))
-	if strings.IndexByte(name, '.') != -1 {
-		s.html.WriteString(".wiki")
-	}
-	s.html.WriteString("\" class=\"link\">")
-	s.html.WriteString(template.HTMLEscapeString(name))
-	s.html.WriteString("</a>")
+	appendInline(s, &ast.Node{Kind: ast.WikiLink, Name: name})
 
 	s.index += 2 + ket + 2
 	return true
@@ -492,23 +409,10 @@ func link(s *state) bool {
 
 	checked := u.String()
 
-	s.text.WriteString(checked)
-
-	s.plain.WriteString(checked)
-
-	htmlURL := template.HTMLEscapeString(checked)
 	if extFound && domainFound {
-		s.html.WriteString("<img src=\"")
-		s.html.WriteString(htmlURL)
-		s.html.WriteString("\" alt=\"")
-		s.html.WriteString(htmlURL)
-		s.html.WriteString("\" />")
+		appendInline(s, &ast.Node{Kind: ast.Image, Text: checked})
 	} else {
-		s.html.WriteString("<a href=\"")
-		s.html.WriteString(htmlURL)
-		s.html.WriteString("\" class=\"link\">")
-		s.html.WriteString(htmlURL)
-		s.html.WriteString("</a>")
+		appendInline(s, &ast.Node{Kind: ast.Link, Text: checked})
 	}
 
 	s.index += len(rawURL)
@@ -518,27 +422,21 @@ func link(s *state) bool {
 func interLink(s *state) bool {
 	line := s.input[s.index:s.lineEnd]
 	for _, item := range s.config.links {
-		if strings.HasPrefix(line, item.Key + ":") {
-			end := nonURLIndex(line[len(item.Key) + 1:])
-			rawURL := line[:len(item.Key) + 1 + end]
+		if strings.HasPrefix(line, item.Key+":") {
+			end := nonURLIndex(line[len(item.Key)+1:])
+			rawURL := line[:len(item.Key)+1+end]
 
-			_, err := url.Parse(rawURL[len(item.Key) + 1:])
+			_, err := url.Parse(rawURL[len(item.Key)+1:])
 			if err != nil {
 				continue
 			}
 
-			s.text.WriteString(rawURL)
-
-			s.plain.WriteString(rawURL)
-
-			htmlURL := template.HTMLEscapeString(rawURL)
-			htmlPath := template.HTMLEscapeString(rawURL[len(item.Key) + 1:])
-			s.html.WriteString("<a href=\"")
-			s.html.WriteString(item.URL)
-			s.html.WriteString(htmlPath)
-			s.html.WriteString("\" class=\"link\">")
-			s.html.WriteString(htmlURL)
-			s.html.WriteString("</a>")
+			appendInline(s, &ast.Node{
+				Kind: ast.InterLink,
+				Text: rawURL,
+				Name: rawURL[len(item.Key)+1:],
+				URL:  item.URL,
+			})
 
 			s.index += len(rawURL)
 			return true
@@ -547,47 +445,27 @@ func interLink(s *state) bool {
 	return false
 }
 
-func html(s *state) bool {
-	c := s.input[s.index]
-	if c == '&' {
-		s.index += 1
-		s.text.WriteString("&")
-		s.plain.WriteString("&")
-		s.html.WriteString("&amp;")
-		return true
-	} else if c == '<' {
-		s.index += 1
-		s.text.WriteString("<")
-		s.plain.WriteString("<")
-		s.html.WriteString("&lt;")
-		return true
-	} else if c == '>' {
-		s.index += 1
-		s.text.WriteString(">")
-		s.plain.WriteString(">")
-		s.html.WriteString("&gt;")
-		return true
-	} else if c == '"' {
-		s.index += 1
-		s.text.WriteString("\"")
-		s.plain.WriteString("\"")
-		s.html.WriteString("&quot;")
-		return true
+// escapedBar takes escaped bar in table cell as text.
+func escapedBar(s *state) bool {
+	if !s.cell || !strings.HasPrefix(s.input[s.index:s.lineEnd], "\\|") {
+		return false
 	}
-	return false
+	appendText(s, "|")
+	s.index += 2
+	return true
 }
 
 func raw(s *state) {
-	r, size := utf8.DecodeRuneInString(s.input[s.index:])
+	_, size := utf8.DecodeRuneInString(s.input[s.index:])
+	appendText(s, s.input[s.index:s.index+size])
 	s.index += size
-	s.text.WriteRune(r)
-	s.plain.WriteRune(r)
-	s.html.WriteRune(r)
 }
 
 func handleLine(s *state) {
 	for s.index < s.lineEnd {
-		if math(s) {
+		if escapedBar(s) {
+			continue
+		} else if math(s) {
 			continue
 		} else if strong(s) {
 			continue
@@ -605,8 +483,6 @@ func handleLine(s *state) {
 			continue
 		} else if link(s) {
 			continue
-		} else if html(s) {
-			continue
 		} else {
 			raw(s)
 		}
@@ -666,8 +542,26 @@ func nextLine(s *state) {
 	s.lineEnd = nonBlank
 }
 
+// peekLine returns next line without moving to it.
+func peekLine(s *state) string {
+	if s.nextLine >= len(s.input) {
+		return ""
+	}
+	end := len(s.input)
+	lineFeed := strings.IndexByte(s.input[s.nextLine:], '\n')
+	if lineFeed != -1 {
+		end = s.nextLine + lineFeed
+	}
+	return strings.TrimRight(s.input[s.nextLine:end], " \t\r")
+}
+
 const headingMaxLevel = 6
 
+// headingMark returns heading markup for level.
+func headingMark(level int) string {
+	return strings.Repeat("#", level)
+}
+
 func parseHeading(s *state) (int, string, bool) {
 	if s.block == blockRaw ||
 		s.block == blockCode ||
@@ -678,30 +572,191 @@ func parseHeading(s *state) (int, string, bool) {
 		return 0, "", false
 	}
 
-	if strings.HasPrefix(s.line, "# ") {
-		return 1, s.line[2:], true
+	for level := 1; level <= headingMaxLevel; level++ {
+		mark := headingMark(level)
+		if strings.HasPrefix(s.line, mark+" ") {
+			return level, s.line[len(mark)+1:], true
+		}
+	}
+
+	return 0, "", false
+}
+
+func hasNextLine(s *state) bool {
+	return s.nextLine < len(s.input)
+}
+
+// appendBlank appends blank line to text and plain text.
+func appendBlank(s *state) {
+	s.doc.Append(&ast.Node{Kind: ast.Blank})
+}
+
+// parseListItem finds list marker in line
+// and returns indent, ordered flag and prefix length.
+func parseListItem(line string) (int, bool, int, bool) {
+	indent := 0
+	for indent < len(line) && line[indent] == ' ' {
+		indent += 1
+	}
+	rest := line[indent:]
+	if strings.HasPrefix(rest, "- ") || strings.HasPrefix(rest, "* ") ||
+		strings.HasPrefix(rest, "+ ") {
+		return indent, false, indent + 2, true
+	}
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits += 1
+	}
+	if digits > 0 && digits < 10 &&
+		(strings.HasPrefix(rest[digits:], ". ") ||
+			strings.HasPrefix(rest[digits:], ") ")) {
+		return indent, true, indent + digits + 2, true
+	}
+	return 0, false, 0, false
+}
+
+func isListItem(s *state, line string) bool {
+	indent, _, _, ok := parseListItem(line)
+	if !ok {
+		return false
 	}
-	if strings.HasPrefix(s.line, "## ") {
-		return 2, s.line[3:], true
+	// nested items are allowed only in list block
+	return indent == 0 || s.block == blockList
+}
+
+func openList(s *state, indent int, ordered bool) {
+	list := &ast.Node{Kind: ast.List, Ordered: ordered}
+	if len(s.lists) < 1 {
+		s.doc.Append(list)
+	} else {
+		// nested list in current item
+		s.lists[len(s.lists)-1].node.Last().Append(list)
 	}
-	if strings.HasPrefix(s.line, "### ") {
-		return 3, s.line[4:], true
+	s.lists = append(s.lists, listLevel{node: list, indent: indent})
+}
+
+func closeList(s *state) {
+	s.lists = s.lists[:len(s.lists)-1]
+}
+
+func openListItem(s *state, indent int, ordered bool) {
+	// item between two open levels joins deeper one
+	for len(s.lists) > 1 && indent <= s.lists[len(s.lists)-2].indent {
+		closeList(s)
 	}
-	if strings.HasPrefix(s.line, "#### ") {
-		return 4, s.line[5:], true
+	if len(s.lists) < 1 || indent > s.lists[len(s.lists)-1].indent {
+		openList(s, indent, ordered)
+	} else if s.lists[len(s.lists)-1].node.Ordered != ordered {
+		closeList(s)
+		openList(s, indent, ordered)
 	}
-	if strings.HasPrefix(s.line, "##### ") {
-		return 5, s.line[6:], true
+
+	list := s.lists[len(s.lists)-1].node
+	s.inline = list.Append(&ast.Node{Kind: ast.ListItem})
+}
+
+func isTableRow(line string) bool {
+	return len(line) >= 2 && line[0] == '|'
+}
+
+// splitTableRow splits table row line into cell ranges.
+// Bars in wiki links and escaped bars do not split cells.
+func splitTableRow(line string) [][2]int {
+	var cells [][2]int
+	start := 1
+	for i := 1; i < len(line); i++ {
+		if strings.HasPrefix(line[i:], "[[") {
+			if ket := strings.Index(line[i+2:], "]]"); ket != -1 {
+				i += 2 + ket + 1
+				continue
+			}
+		}
+		if line[i] == '\\' {
+			i += 1
+			continue
+		}
+		if line[i] == '|' {
+			cells = append(cells, [2]int{start, i})
+			start = i + 1
+		}
 	}
-	if strings.HasPrefix(s.line, "###### ") {
-		return 6, s.line[7:], true
+	// closing bar is optional
+	if start < len(line) || len(cells) < 1 {
+		cells = append(cells, [2]int{start, len(line)})
 	}
+	return cells
+}
 
-	return 0, "", false
+// parseTableAligns parses delimiter row such as | --- | :-: | --: |.
+func parseTableAligns(line string) ([]string, bool) {
+	if !isTableRow(line) {
+		return nil, false
+	}
+	var aligns []string
+	for _, cell := range splitTableRow(line) {
+		delim := strings.TrimSpace(line[cell[0]:cell[1]])
+		left := strings.HasPrefix(delim, ":")
+		right := strings.HasSuffix(delim, ":")
+		delim = strings.Trim(delim, ":")
+		if delim == "" || strings.Trim(delim, "-") != "" {
+			return nil, false
+		}
+		if left && right {
+			aligns = append(aligns, "center")
+		} else if left {
+			aligns = append(aligns, "left")
+		} else if right {
+			aligns = append(aligns, "right")
+		} else {
+			aligns = append(aligns, "")
+		}
+	}
+	return aligns, true
 }
 
-func hasNextLine(s *state) bool {
-	return s.nextLine < len(s.input)
+// applyTableCell applies inline formatting on cell
+// in range of input text.
+func applyTableCell(s *state, row *ast.Node, start int, end int, header bool) {
+	for start < end && (s.input[start] == ' ' || s.input[start] == '\t') {
+		start += 1
+	}
+	for end > start && (s.input[end-1] == ' ' || s.input[end-1] == '\t') {
+		end -= 1
+	}
+
+	lineEnd := s.lineEnd
+	s.inline = row.Append(&ast.Node{Kind: ast.TableCell, Header: header})
+	s.index = start
+	s.lineEnd = end
+	s.cell = true
+
+	handleLine(s)
+	closeDecos(s)
+
+	s.cell = false
+	s.lineEnd = lineEnd
+}
+
+func closeTable(s *state) {
+	// keep paragraph separator in text and plain text
+	if s.index < len(s.input) {
+		line := s.input[s.index:s.lineEnd]
+		if isBlank(line) {
+			appendBlank(s)
+		}
+	}
+}
+
+// tableRow appends cells in current line to table.
+func tableRow(s *state, header bool) {
+	lineStart := s.index
+	row := s.node.Append(&ast.Node{Kind: ast.TableRow})
+	for _, cell := range splitTableRow(s.line) {
+		start := lineStart + cell[0]
+		end := lineStart + cell[1]
+		applyTableCell(s, row, start, end, header)
+	}
+	s.index = s.lineEnd
 }
 
 func handleBlock(s *state) bool {
@@ -709,15 +764,7 @@ func handleBlock(s *state) bool {
 	if (s.block == blockNone || s.block == blockParagraph) &&
 		strings.HasPrefix(s.line, "-") && strings.HasSuffix(s.line, "-") {
 		ensureBlock(s, blockHorizon)
-
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
-
-		s.plain.WriteString(s.line)
-		s.plain.WriteString("\n")
-
-		s.html.WriteString("<hr />\n")
-
+		s.node.Text = s.line
 		ensureBlock(s, blockNone)
 		nextLine(s)
 		return true
@@ -728,16 +775,7 @@ func handleBlock(s *state) bool {
 		s.block != blockMath && s.prevLine == "" &&
 		strings.HasPrefix(s.line, " ") {
 		ensureBlock(s, blockRaw)
-
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
-
-		s.plain.WriteString(s.line)
-		s.plain.WriteString("\n")
-
-		s.html.WriteString(template.HTMLEscapeString(s.line))
-		s.html.WriteString("\n")
-
+		s.node.Lines = append(s.node.Lines, s.line)
 		nextLine(s)
 		return true
 	}
@@ -745,8 +783,6 @@ func handleBlock(s *state) bool {
 	// open code block
 	if s.block != blockRaw && s.block != blockCode &&
 		s.block != blockMath && s.line == "```" {
-		s.text.WriteString("```\n")
-		s.plain.WriteString("\n")
 		ensureBlock(s, blockCode)
 		nextLine(s)
 		return true
@@ -772,8 +808,6 @@ func handleBlock(s *state) bool {
 	// close code block
 	if s.block == blockCode && s.line == "```" {
 		ensureBlock(s, blockNone)
-		s.text.WriteString("```\n")
-		s.plain.WriteString("\n")
 		nextLine(s)
 		return true
 	}
@@ -793,92 +827,115 @@ func handleBlock(s *state) bool {
 		return true
 	}
 
+	// table of contents
+	if s.block != blockRaw && s.block != blockCode &&
+		s.block != blockMath && s.line == "<<toc>>" {
+		ensureBlock(s, blockNone)
+		s.doc.Append(&ast.Node{Kind: ast.TOC})
+		nextLine(s)
+		return true
+	}
+
+	// redirect
+	if s.block != blockRaw && s.block != blockCode && s.block != blockMath {
+		if target, ok := ast.ParseRedirect(s.line); ok {
+			ensureBlock(s, blockNone)
+			s.doc.Append(&ast.Node{Kind: ast.Redirect, Name: target})
+			nextLine(s)
+			return true
+		}
+	}
+
 	// headings
 	if level, title, ok := parseHeading(s); ok {
 		if !isBlank(s.prevLine) {
-			s.text.WriteString("\n")
-			s.plain.WriteString("\n")
+			appendBlank(s)
 		}
 
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
-
-		s.plain.WriteString(title)
-		s.plain.WriteString("\n")
+		ensureBlock(s, blockNone)
+		heading := s.doc.Append(&ast.Node{
+			Kind:   ast.Heading,
+			Level:  level,
+			Text:   title,
+			Offset: s.index,
+		})
+		if level == 1 && s.title == "" {
+			s.title = title
+			heading.Title = true
+		}
 
 		if hasNextLine(s) {
-			s.text.WriteString("\n")
-			s.plain.WriteString("\n")
+			appendBlank(s)
 		}
 
-		if level == 1 && s.title == "" {
-			s.title = title
-			nextLine(s)
-		} else {
-			ensureBlock(s, blockNone)
-			titleHTML := template.HTMLEscapeString(title)
-			levelStr := strconv.Itoa(level)
-			s.html.WriteString("<h")
-			s.html.WriteString(levelStr)
-			s.html.WriteString(">")
-			s.html.WriteString(titleHTML)
-			s.html.WriteString("</h")
-			s.html.WriteString(levelStr)
-			s.html.WriteString(">\n")
-			nextLine(s)
-		}
+		nextLine(s)
 		return true
 	}
 
 	// in raw block
 	if s.block == blockRaw && strings.HasPrefix(s.line, " ") {
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
-
-		s.plain.WriteString(s.line)
-		s.plain.WriteString("\n")
-
-		s.html.WriteString(template.HTMLEscapeString(s.line))
-		s.html.WriteString("\n")
-
+		s.node.Lines = append(s.node.Lines, s.line)
 		nextLine(s)
 		return true
 	}
 
 	// in code block
 	if s.block == blockCode {
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
-
-		s.plain.WriteString(s.line)
-		s.plain.WriteString("\n")
-
-		s.html.WriteString(template.HTMLEscapeString(s.line))
-		s.html.WriteString("\n")
-
+		s.node.Lines = append(s.node.Lines, s.line)
 		nextLine(s)
 		return true
 	}
 
 	// in math block
 	if s.block == blockMath {
-		s.text.WriteString(s.line)
-		s.text.WriteString("\n")
+		s.node.Lines = append(s.node.Lines, s.line)
+		nextLine(s)
+		return true
+	}
 
-		s.plain.WriteString(s.line)
-		s.plain.WriteString("\n")
+	// tables
+	if s.block == blockTable && isTableRow(s.line) {
+		tableRow(s, false)
+		nextLine(s)
+		trimTrailingBlanks(s)
+		return true
+	}
+	if isTableRow(s.line) {
+		// table begins with header row and delimiter row
+		if aligns, ok := parseTableAligns(peekLine(s)); ok {
+			ensureBlock(s, blockTable)
+			tableRow(s, true)
+			s.node.Aligns = aligns
+			nextLine(s)
+			nextLine(s)
+			trimTrailingBlanks(s)
+			return true
+		}
+	}
 
-		s.html.WriteString(template.HTMLEscapeString(s.line))
-		s.html.WriteString("\n")
+	// list items
+	if isListItem(s, s.line) {
+		indent, ordered, size, _ := parseListItem(s.line)
+		ensureBlock(s, blockList)
+		openListItem(s, indent, ordered)
+		s.index += size
+		handleLine(s)
+		closeDecos(s)
 
 		nextLine(s)
+		if s.index < len(s.input) {
+			line := s.input[s.index:s.lineEnd]
+			if isBlank(line) {
+				appendBlank(s)
+			}
+		}
+		trimTrailingBlanks(s)
 		return true
 	}
 
 	if s.line == "" {
 		ensureBlock(s, blockNone)
-		s.text.WriteString("\n")
-		s.plain.WriteString("\n")
+		appendBlank(s)
 		nextLine(s)
 		return true
 	}
@@ -902,17 +959,16 @@ func trimTrailingBlanks(s *state) {
 	}
 }
 
-// Apply applies Nomark formatting on specified title and text.
-func Apply(fc formatConfig, title string, text string) (
-	string, string, string, string,
-) {
+// Parse parses Markdown text into document tree.
+// Page title found in text is set to text of document node.
+func Parse(fc formatConfig, text string) *ast.Node {
 	s := state{
 		config:    fc,
 		input:     text,
 		index:     0,
-		text:      new(strings.Builder),
-		plain:     new(strings.Builder),
-		html:      new(strings.Builder),
+		doc:       &ast.Node{Kind: ast.Document},
+		node:      nil,
+		inline:    nil,
 		block:     blockNone,
 		nextLine:  0,
 		lineEnd:   0,
@@ -921,6 +977,9 @@ func Apply(fc formatConfig, title string, text string) (
 		title:     "",
 		outerDeco: decoNone,
 		innerDeco: decoNone,
+		outer:     nil,
+		inner:     nil,
+		lists:     nil,
 	}
 
 	// ignore beginning blank lines
@@ -943,46 +1002,37 @@ func Apply(fc formatConfig, title string, text string) (
 
 		// ensure normal paragraph is open
 		ensureBlock(&s, blockParagraph)
-		// convert starting spaces to non-breaking spaces
+		line := s.node.Append(&ast.Node{Kind: ast.Line})
+		s.inline = line
+		// count starting spaces, which are rendered as
+		// non-breaking spaces
 		// TODO handle tabs
 		for s.index < s.lineEnd {
 			c := s.input[s.index]
 			if c != ' ' {
 				break
 			}
-			s.text.WriteString(" ")
-			s.plain.WriteString(" ")
-			s.html.WriteString("&nbsp;")
+			line.Indent += 1
 			s.index += 1
 		}
 		// handle normal markups in line
 		// note that nextLine might be called in handleLine
 		handleLine(&s)
 
-		// add LF to text and plain text buffers
-		s.text.WriteString("\n")
-		s.plain.WriteString("\n")
-
 		// find next line
 		nextLine(&s)
 
 		// here after, range check is required
 		// since s.index might be advanced
 
-		// add blank lines to text and plain text buffers
+		// add blank lines to text and plain text
 		if s.index < len(s.input) {
-			line := s.input[s.index:s.lineEnd]
-			if isBlank(line) {
-				s.text.WriteString("\n")
-				s.plain.WriteString("\n")
+			next := s.input[s.index:s.lineEnd]
+			if isBlank(next) {
+				appendBlank(&s)
 			}
 		}
 
-		// add LF to HTML buffer
-		if s.block == blockParagraph {
-			s.html.WriteString("\n")
-		}
-
 		// trim trailing blank lines if exist
 		trimTrailingBlanks(&s)
 	}
@@ -991,11 +1041,38 @@ func Apply(fc formatConfig, title string, text string) (
 	// ensure no blocks are open
 	ensureBlock(&s, blockNone)
 
+	s.doc.Text = s.title
+	return s.doc
+}
+
+// style hides Markdown markup in HTML.
+var style = ast.Style{Markup: false}
+
+// Render renders document tree parsed from text
+// and returns title, normalized text, plain text and HTML.
+// Section edit links are added to headings if edit is true,
+// and wiki links to pages in missing are shown as links to edit.
+func Render(fc formatConfig, title string, doc *ast.Node, edit bool, missing map[string]bool) (
+	string, string, string, string,
+) {
 	// if no title is found from input text, use original title
-	if s.title == "" {
-		s.title = title
+	if doc.Text == "" {
+		doc.Text = title
 	}
 
-	// return result
-	return s.title, s.text.String(), s.plain.String(), s.html.String()
+	text := Emit(doc)
+	plain := ast.Plain(doc)
+	ast.InsertTOC(doc, fc.toc)
+
+	htmlStyle := style
+	htmlStyle.Edit = edit
+	htmlStyle.Missing = missing
+	return doc.Text, text, plain, ast.HTML(doc, htmlStyle)
+}
+
+// Apply applies Markdown formatting on specified title and text.
+func Apply(fc formatConfig, title string, text string) (
+	string, string, string, string,
+) {
+	return Render(fc, title, Parse(fc, text), false, nil)
 }
@@ -89,7 +89,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"## Test\n",
 			"Test\n",
-			"<h2>Test</h2>\n",
+			"<h2 id=\"h-test\">Test</h2>\n",
 		},
 		{
 			"heading 3",
@@ -98,7 +98,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"### Test\n",
 			"Test\n",
-			"<h3>Test</h3>\n",
+			"<h3 id=\"h-test\">Test</h3>\n",
 		},
 		{
 			"heading 4",
@@ -107,7 +107,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"#### Test\n",
 			"Test\n",
-			"<h4>Test</h4>\n",
+			"<h4 id=\"h-test\">Test</h4>\n",
 		},
 		{
 			"heading 5",
@@ -116,7 +116,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"##### Test\n",
 			"Test\n",
-			"<h5>Test</h5>\n",
+			"<h5 id=\"h-test\">Test</h5>\n",
 		},
 		{
 			"heading 6",
@@ -125,7 +125,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"###### Test\n",
 			"Test\n",
-			"<h6>Test</h6>\n",
+			"<h6 id=\"h-test\">Test</h6>\n",
 		},
 		{
 			"strong",
@@ -163,6 +163,15 @@ func TestApply(t *testing.T) {
 			"See also Test page.\n",
 			"<p>\nSee also <a href=\"/Test\" class=\"link\">Test</a> page.\n</p>\n",
 		},
+		{
+			"redirect",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"Test\n",
+			"<p class=\"redirect\">Redirect to <a href=\"/Test\" class=\"link\">Test</a></p>\n",
+		},
 		{
 			"code",
 			"WikiPage",
@@ -190,6 +199,87 @@ func TestApply(t *testing.T) {
 			"Mass is energy:\nE=mc^2\n\n",
 			"<p>\nMass is energy:\n</p>\n<div>\n<nomark-math class=\"mathjax\">\\[E=mc^2\n\\]</nomark-math>\n</div>\n",
 		},
+		{
+			"list",
+			"WikiPage",
+			"- apple\n* banana\n+ cherry\n",
+			"WikiPage",
+			"- apple\n- banana\n- cherry\n",
+			"apple\nbanana\ncherry\n",
+			"<ul>\n<li>apple</li>\n<li>banana</li>\n<li>cherry</li>\n</ul>\n",
+		},
+		{
+			"ordered list",
+			"WikiPage",
+			"Steps:\n1. open\n1) close\n",
+			"WikiPage",
+			"Steps:\n1. open\n2. close\n",
+			"Steps:\nopen\nclose\n",
+			"<p>\nSteps:\n</p>\n<ol>\n<li>open</li>\n<li>close</li>\n</ol>\n",
+		},
+		{
+			"nested list",
+			"WikiPage",
+			"1. fruits\n    - apple\n2. **nuts**\n\nDone.\n",
+			"WikiPage",
+			"1. fruits\n   - apple\n2. **nuts**\n\nDone.\n",
+			"fruits\napple\nnuts\n\nDone.\n",
+			"<ol>\n<li>fruits\n<ul>\n<li>apple</li>\n</ul>\n</li>\n<li><strong>nuts</strong></li>\n</ol>\n<p>\nDone.\n</p>\n",
+		},
+		{
+			"uneven nested list",
+			"WikiPage",
+			"- a\n    - b\n  - c\n- d\n",
+			"WikiPage",
+			"- a\n  - b\n  - c\n- d\n",
+			"a\nb\nc\nd\n",
+			"<ul>\n<li>a\n<ul>\n<li>b</li>\n<li>c</li>\n</ul>\n</li>\n<li>d</li>\n</ul>\n",
+		},
+		{
+			"table",
+			"WikiPage",
+			"| 名前 | Age |\n| :-: | - |\n| **Aki** | 17 |\n\nDone.\n",
+			"WikiPage",
+			"| 名前    | Age |\n| :-----: | --- |\n| **Aki** | 17  |\n\nDone.\n",
+			"名前\tAge\nAki\t17\n\nDone.\n",
+			"<table>\n<tr>\n<th style=\"text-align: center;\">名前</th>\n<th>Age</th>\n</tr>\n<tr>\n<td style=\"text-align: center;\"><strong>Aki</strong></td>\n<td>17</td>\n</tr>\n</table>\n<p>\nDone.\n</p>\n",
+		},
+		{
+			"short header row",
+			"WikiPage",
+			"| a |\n|---|\n| 1 | 2 | 3 |\n",
+			"WikiPage",
+			"| a   |     |     |\n| --- | --- | --- |\n| 1   | 2   | 3   |\n",
+			"a\n1\t2\t3\n",
+			"<table>\n<tr>\n<th>a</th>\n<th></th>\n<th></th>\n</tr>\n<tr>\n<td>1</td>\n<td>2</td>\n<td>3</td>\n</tr>\n</table>\n",
+		},
+		{
+			"table escaped bar",
+			"WikiPage",
+			"| a \\| b | z |\n|---|---|\n| **x\\|y** | z |\n",
+			"WikiPage",
+			"| a \\| b   | z   |\n| -------- | --- |\n| **x\\|y** | z   |\n",
+			"a | b\tz\nx|y\tz\n",
+			"<table>\n<tr>\n<th>a | b</th>\n<th>z</th>\n</tr>\n<tr>\n<td><strong>x|y</strong></td>\n<td>z</td>\n</tr>\n</table>\n",
+		},
+		{
+			"table wikilink with bar",
+			"WikiPage",
+			"| [[Page|label]] | b |\n|---|---|\n",
+			"WikiPage",
+			"| [[Page|label]] | b   |\n| -------------- | --- |\n",
+			"Page|label\tb\n",
+			"<table>\n<tr>\n<th><a href=\"/Page%7Clabel\" class=\"link\">Page|label</a></th>\n<th>b</th>\n</tr>\n</table>\n",
+		},
+		{
+			"table without delimiter",
+			"WikiPage",
+			"|a|b|\n",
+			"WikiPage",
+			"|a|b|\n",
+			"|a|b|\n",
+			"<p>\n|a|b|\n</p>\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,223 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+type emitter struct {
+	buf strings.Builder
+}
+
+// emitInlines writes inline nodes.
+// Bars in text are escaped in table cell.
+func emitInlines(buf *strings.Builder, nodes []*ast.Node, cell bool) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case ast.Text:
+			if cell {
+				buf.WriteString(strings.ReplaceAll(n.Text, "|", "\\|"))
+			} else {
+				buf.WriteString(n.Text)
+			}
+		case ast.Link, ast.Image, ast.InterLink:
+			buf.WriteString(n.Text)
+		case ast.Strong:
+			emitDeco(buf, n, "**", cell)
+		case ast.Emphasis:
+			emitDeco(buf, n, "*", cell)
+		case ast.InlineMath:
+			buf.WriteString("%%")
+			buf.WriteString(n.Text)
+			buf.WriteString("%%")
+		case ast.WikiLink:
+			if isCamel(n.Name) {
+				buf.WriteString(n.Name)
+			} else {
+				buf.WriteString("[[")
+				buf.WriteString(n.Name)
+				buf.WriteString("]]")
+			}
+		}
+	}
+}
+
+// emitDeco writes decoration with its source markup if known.
+func emitDeco(buf *strings.Builder, n *ast.Node, mark string, cell bool) {
+	if n.Mark != "" {
+		mark = n.Mark
+	}
+	buf.WriteString(mark)
+	emitInlines(buf, n.Children, cell)
+	if n.Closed {
+		buf.WriteString(mark)
+	}
+}
+
+// isCamel tells name is written as CamelCase link.
+func isCamel(name string) bool {
+	s := state{input: name, lineEnd: len(name), doc: &ast.Node{}}
+	s.inline = s.doc
+	return camel(&s) && s.index == len(name)
+}
+
+func (e *emitter) inlineText(nodes []*ast.Node) string {
+	var buf strings.Builder
+	emitInlines(&buf, nodes, false)
+	return buf.String()
+}
+
+func (e *emitter) cellText(nodes []*ast.Node) string {
+	var buf strings.Builder
+	emitInlines(&buf, nodes, true)
+	return buf.String()
+}
+
+func (e *emitter) lines(lines []string) {
+	for _, line := range lines {
+		e.buf.WriteString(line)
+		e.buf.WriteString("\n")
+	}
+}
+
+func (e *emitter) list(n *ast.Node, indent int) {
+	for i, item := range n.Children {
+		mark := "-"
+		if n.Ordered {
+			mark = strconv.Itoa(i+1) + "."
+		}
+		e.buf.WriteString(strings.Repeat(" ", indent))
+		e.buf.WriteString(mark)
+		e.buf.WriteString(" ")
+		inlines, nested := ast.SplitListItem(item)
+		e.buf.WriteString(e.inlineText(inlines))
+		e.buf.WriteString("\n")
+		// normalized indent is sum of parent marker widths
+		for _, list := range nested {
+			e.list(list, indent+len(mark)+1)
+		}
+	}
+}
+
+func (e *emitter) table(n *ast.Node) {
+	columns := ast.TableColumns(n)
+	texts := make([][]string, len(n.Children))
+	widths := make([]int, columns)
+	for i := range widths {
+		if n.Aligns != nil {
+			widths[i] = 3
+		}
+	}
+	for r, row := range n.Children {
+		for i, cell := range row.Children {
+			text := e.cellText(cell.Children)
+			texts[r] = append(texts[r], text)
+			w := ast.TextWidth(text)
+			if w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	for r, row := range texts {
+		e.buf.WriteString("|")
+		for i := 0; i < columns; i++ {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			e.buf.WriteString(" ")
+			e.buf.WriteString(text)
+			e.buf.WriteString(strings.Repeat(" ", widths[i]-ast.TextWidth(text)))
+			e.buf.WriteString(" |")
+		}
+		e.buf.WriteString("\n")
+
+		// delimiter row follows header row
+		if r == 0 && n.Aligns != nil {
+			e.buf.WriteString("|")
+			for i := 0; i < columns; i++ {
+				align := ""
+				if i < len(n.Aligns) {
+					align = n.Aligns[i]
+				}
+				e.buf.WriteString(" ")
+				if align == "center" {
+					e.buf.WriteString(":")
+					e.buf.WriteString(strings.Repeat("-", widths[i]-2))
+					e.buf.WriteString(":")
+				} else if align == "left" {
+					e.buf.WriteString(":")
+					e.buf.WriteString(strings.Repeat("-", widths[i]-1))
+				} else if align == "right" {
+					e.buf.WriteString(strings.Repeat("-", widths[i]-1))
+					e.buf.WriteString(":")
+				} else {
+					e.buf.WriteString(strings.Repeat("-", widths[i]))
+				}
+				e.buf.WriteString(" |")
+			}
+			e.buf.WriteString("\n")
+		}
+	}
+}
+
+func (e *emitter) block(n *ast.Node) {
+	switch n.Kind {
+	case ast.Blank:
+		e.buf.WriteString("\n")
+	case ast.Paragraph:
+		for _, line := range n.Children {
+			e.buf.WriteString(strings.Repeat(" ", line.Indent))
+			e.buf.WriteString(e.inlineText(line.Children))
+			e.buf.WriteString("\n")
+		}
+	case ast.Heading:
+		level := n.Level
+		if level > headingMaxLevel {
+			level = headingMaxLevel
+		}
+		e.buf.WriteString(headingMark(level))
+		e.buf.WriteString(" ")
+		e.buf.WriteString(n.Text)
+		e.buf.WriteString("\n")
+	case ast.Horizon:
+		if strings.HasPrefix(n.Text, "-") && strings.HasSuffix(n.Text, "-") {
+			e.buf.WriteString(n.Text)
+		} else {
+			e.buf.WriteString("----")
+		}
+		e.buf.WriteString("\n")
+	case ast.Raw:
+		e.lines(n.Lines)
+	case ast.Code:
+		e.buf.WriteString("```\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("```\n")
+	case ast.Math:
+		e.buf.WriteString("%%%\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("%%%\n\n")
+	case ast.List:
+		e.list(n, 0)
+	case ast.Table:
+		e.table(n)
+	case ast.TOC:
+		e.buf.WriteString("<<toc>>\n\n")
+	case ast.Redirect:
+		e.buf.WriteString("<<redirect ")
+		e.buf.WriteString(n.Name)
+		e.buf.WriteString(">>\n")
+	}
+}
+
+// Emit writes document tree as Markdown text.
+func Emit(doc *ast.Node) string {
+	e := emitter{}
+	for _, n := range doc.Children {
+		e.block(n)
+	}
+	return e.buf.String()
+}
@@ -0,0 +1,24 @@
+package markdown
+
+import "testing"
+
+func TestEmitStable(t *testing.T) {
+	tests := []string{
+		"# Title\n\nThis is **strong**, __strong__, *em* and _em_.\nWikiPage and [[page]].\n",
+		"+ one\n* two\n  1) three\n  2) four\n",
+		"| a | b |\n| :-- | --: |\n| c | d |\n",
+		"```\ncode\n```\n\n%%%\nx^2\n%%%\n",
+		"text\n\n raw\n\n----\n",
+		"| a |\n|---|\n| 1 | 2 | 3 |\n",
+		"| a \\| b | z |\n|---|---|\n| **x\\|y** | z |\n",
+		"| [[Page|label]] | b |\n|---|---|\n",
+	}
+
+	for _, text := range tests {
+		first := Emit(Parse(mockCfg, text))
+		second := Emit(Parse(mockCfg, first))
+		if second != first {
+			t.Errorf("Emit(Parse(%q)) = %q, want %q", first, second, first)
+		}
+	}
+}
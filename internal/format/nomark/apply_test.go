@@ -89,7 +89,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"!!!! Test !!!!\n",
 			"Test\n",
-			"<h2><span class=\"markup\">!!!!</span> Test <span class=\"markup\">!!!!</span></h2>\n",
+			"<h2 id=\"h-test\"><span class=\"markup\">!!!!</span> Test <span class=\"markup\">!!!!</span></h2>\n",
 		},
 		{
 			"heading 3",
@@ -98,7 +98,7 @@ func TestApply(t *testing.T) {
 			"WikiPage",
 			"!!! Test !!!\n",
 			"Test\n",
-			"<h3><span class=\"markup\">!!!</span> Test <span class=\"markup\">!!!</span></h3>\n",
+			"<h3 id=\"h-test\"><span class=\"markup\">!!!</span> Test <span class=\"markup\">!!!</span></h3>\n",
 		},
 		{
 			"strong",
@@ -127,6 +127,15 @@ func TestApply(t *testing.T) {
 			"See also Test page.\n",
 			"<p>\nSee also <span class=\"markup\">[[</span><a href=\"/Test\" class=\"link\">Test</a><span class=\"markup\">]]</span> page.\n</p>\n",
 		},
+		{
+			"redirect",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"WikiPage",
+			"<<redirect Test>>\n",
+			"Test\n",
+			"<p class=\"redirect\">Redirect to <a href=\"/Test\" class=\"link\">Test</a></p>\n",
+		},
 		{
 			"code",
 			"WikiPage",
@@ -154,6 +163,90 @@ func TestApply(t *testing.T) {
 			"Mass is energy:\nE=mc^2\n\n",
 			"<p>\nMass is energy:\n</p>\n<div>\n<span class=\"markup\">%%%</span>\n<nomark-math class=\"mathjax\">\\[E=mc^2\n\\]</nomark-math>\n<span class=\"markup\">%%%</span>\n</div>\n",
 		},
+		{
+			"list",
+			"WikiPage",
+			"- apple\n* banana\n",
+			"WikiPage",
+			"- apple\n- banana\n",
+			"apple\nbanana\n",
+			"<ul>\n<li><span class=\"markup\">-</span> apple</li>\n<li><span class=\"markup\">-</span> banana</li>\n</ul>\n",
+		},
+		{
+			"ordered list",
+			"WikiPage",
+			"Steps:\n1. open\n1. close\n",
+			"WikiPage",
+			"Steps:\n1. open\n2. close\n",
+			"Steps:\nopen\nclose\n",
+			"<p>\nSteps:\n</p>\n<ol>\n<li><span class=\"markup\">1.</span> open</li>\n<li><span class=\"markup\">2.</span> close</li>\n</ol>\n",
+		},
+		{
+			"nested list",
+			"WikiPage",
+			"1. fruits\n    - apple\n2. **nuts**\n\nDone.\n",
+			"WikiPage",
+			"1. fruits\n   - apple\n2. **nuts**\n\nDone.\n",
+			"fruits\napple\nnuts\n\nDone.\n",
+			"<ol>\n<li><span class=\"markup\">1.</span> fruits\n<ul>\n<li><span class=\"markup\">-</span> apple</li>\n</ul>\n</li>\n<li><span class=\"markup\">2.</span> <span class=\"markup\">**</span><strong>nuts</strong><span class=\"markup\">**</span></li>\n</ol>\n<p>\nDone.\n</p>\n",
+		},
+		{
+			"uneven nested list",
+			"WikiPage",
+			"- a\n    - b\n  - c\n- d\n",
+			"WikiPage",
+			"- a\n  - b\n  - c\n- d\n",
+			"a\nb\nc\nd\n",
+			"<ul>\n<li><span class=\"markup\">-</span> a\n<ul>\n<li><span class=\"markup\">-</span> b</li>\n<li><span class=\"markup\">-</span> c</li>\n</ul>\n</li>\n<li><span class=\"markup\">-</span> d</li>\n</ul>\n",
+		},
+		{
+			"table",
+			"WikiPage",
+			"|Name|Age|\n|Aki|17|\n",
+			"WikiPage",
+			"| Name | Age |\n| Aki  | 17  |\n",
+			"Name\tAge\nAki\t17\n",
+			"<table>\n<tr>\n<td>Name</td>\n<td>Age</td>\n</tr>\n<tr>\n<td>Aki</td>\n<td>17</td>\n</tr>\n</table>\n",
+		},
+		{
+			"table header",
+			"WikiPage",
+			"|名前|Age|\n|:--|--:|\n|**Aki**|17|\n",
+			"WikiPage",
+			"| 名前    | Age |\n| :------ | --: |\n| **Aki** | 17  |\n",
+			"名前\tAge\nAki\t17\n",
+			"<table>\n<tr>\n<th style=\"text-align: left;\">名前</th>\n<th style=\"text-align: right;\">Age</th>\n</tr>\n<tr>\n<td style=\"text-align: left;\"><span class=\"markup\">**</span><strong>Aki</strong><span class=\"markup\">**</span></td>\n<td style=\"text-align: right;\">17</td>\n</tr>\n</table>\n",
+		},
+		{
+			"short header row",
+			"WikiPage",
+			"| a |\n|---|\n| 1 | 2 | 3 |\n",
+			"WikiPage",
+			"| a   |     |     |\n| --- | --- | --- |\n| 1   | 2   | 3   |\n",
+			"a\n1\t2\t3\n",
+			"<table>\n<tr>\n<th>a</th>\n<th></th>\n<th></th>\n</tr>\n<tr>\n<td>1</td>\n<td>2</td>\n<td>3</td>\n</tr>\n</table>\n",
+		},
+		{
+			"table wikilink with bar",
+			"WikiPage",
+			"| [[Page|label]] | b |\n",
+			"WikiPage",
+			"| [[Page|label]] | b |\n",
+			"Page|label\tb\n",
+			"<table>\n<tr>\n<td><span class=\"markup\">[[</span><a href=\"/Page%7Clabel\" class=\"link\">Page|label</a><span class=\"markup\">]]</span></td>\n<td>b</td>\n</tr>\n</table>\n",
+		},
+		{
+			"toc",
+			"WikiPage",
+			"<<toc>>\n\n!!!! 日本語 見出し !!!!\n\n!!! Sub !!!\n\n!!!! Sub !!!!\n",
+			"WikiPage",
+			"<<toc>>\n\n!!!! 日本語 見出し !!!!\n\n!!! Sub !!!\n\n!!!! Sub !!!!\n",
+			"日本語 見出し\n\nSub\n\nSub\n",
+			"<nav class=\"toc\">\n<ul>\n<li><a href=\"#h-日本語-見出し\">日本語 見出し</a>\n<ul>\n<li><a href=\"#h-sub\">Sub</a></li>\n</ul>\n</li>\n<li><a href=\"#h-sub-2\">Sub</a></li>\n</ul>\n</nav>\n" +
+				"<h2 id=\"h-日本語-見出し\"><span class=\"markup\">!!!!</span> 日本語 見出し <span class=\"markup\">!!!!</span></h2>\n" +
+				"<h3 id=\"h-sub\"><span class=\"markup\">!!!</span> Sub <span class=\"markup\">!!!</span></h3>\n" +
+				"<h2 id=\"h-sub-2\"><span class=\"markup\">!!!!</span> Sub <span class=\"markup\">!!!!</span></h2>\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,209 @@
+package nomark
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+type emitter struct {
+	buf strings.Builder
+}
+
+func emitInlines(buf *strings.Builder, nodes []*ast.Node) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case ast.Text, ast.Link, ast.Image, ast.InterLink:
+			buf.WriteString(n.Text)
+		case ast.Strong:
+			buf.WriteString("**")
+			emitInlines(buf, n.Children)
+			if n.Closed {
+				buf.WriteString("**")
+			}
+		case ast.Emphasis:
+			buf.WriteString("//")
+			emitInlines(buf, n.Children)
+			if n.Closed {
+				buf.WriteString("//")
+			}
+		case ast.InlineMath:
+			buf.WriteString("%%")
+			buf.WriteString(n.Text)
+			buf.WriteString("%%")
+		case ast.WikiLink:
+			if n.Mark == "" && isCamel(n.Name) {
+				buf.WriteString(n.Name)
+			} else {
+				buf.WriteString("[[")
+				buf.WriteString(n.Name)
+				buf.WriteString("]]")
+			}
+		}
+	}
+}
+
+// isCamel tells name is written as CamelCase link.
+func isCamel(name string) bool {
+	s := state{input: name, lineEnd: len(name), doc: &ast.Node{}}
+	s.inline = s.doc
+	return camel(&s) && s.index == len(name)
+}
+
+func (e *emitter) inlineText(nodes []*ast.Node) string {
+	var buf strings.Builder
+	emitInlines(&buf, nodes)
+	return buf.String()
+}
+
+func (e *emitter) lines(lines []string) {
+	for _, line := range lines {
+		e.buf.WriteString(line)
+		e.buf.WriteString("\n")
+	}
+}
+
+func (e *emitter) list(n *ast.Node, indent int) {
+	for i, item := range n.Children {
+		mark := "-"
+		if n.Ordered {
+			mark = strconv.Itoa(i+1) + "."
+		}
+		e.buf.WriteString(strings.Repeat(" ", indent))
+		e.buf.WriteString(mark)
+		e.buf.WriteString(" ")
+		inlines, nested := ast.SplitListItem(item)
+		e.buf.WriteString(e.inlineText(inlines))
+		e.buf.WriteString("\n")
+		// normalized indent is sum of parent marker widths
+		for _, list := range nested {
+			e.list(list, indent+len(mark)+1)
+		}
+	}
+}
+
+func (e *emitter) table(n *ast.Node) {
+	columns := ast.TableColumns(n)
+	texts := make([][]string, len(n.Children))
+	widths := make([]int, columns)
+	for i := range widths {
+		if n.Aligns != nil {
+			widths[i] = 3
+		}
+	}
+	for r, row := range n.Children {
+		for i, cell := range row.Children {
+			text := e.inlineText(cell.Children)
+			texts[r] = append(texts[r], text)
+			w := ast.TextWidth(text)
+			if w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	for r, row := range texts {
+		e.buf.WriteString("|")
+		for i := 0; i < columns; i++ {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			e.buf.WriteString(" ")
+			e.buf.WriteString(text)
+			e.buf.WriteString(strings.Repeat(" ", widths[i]-ast.TextWidth(text)))
+			e.buf.WriteString(" |")
+		}
+		e.buf.WriteString("\n")
+
+		// delimiter row follows header row
+		if r == 0 && n.Aligns != nil {
+			e.buf.WriteString("|")
+			for i := 0; i < columns; i++ {
+				align := ""
+				if i < len(n.Aligns) {
+					align = n.Aligns[i]
+				}
+				e.buf.WriteString(" ")
+				if align == "center" {
+					e.buf.WriteString(":")
+					e.buf.WriteString(strings.Repeat("-", widths[i]-2))
+					e.buf.WriteString(":")
+				} else if align == "left" {
+					e.buf.WriteString(":")
+					e.buf.WriteString(strings.Repeat("-", widths[i]-1))
+				} else if align == "right" {
+					e.buf.WriteString(strings.Repeat("-", widths[i]-1))
+					e.buf.WriteString(":")
+				} else {
+					e.buf.WriteString(strings.Repeat("-", widths[i]))
+				}
+				e.buf.WriteString(" |")
+			}
+			e.buf.WriteString("\n")
+		}
+	}
+}
+
+func (e *emitter) block(n *ast.Node) {
+	switch n.Kind {
+	case ast.Blank:
+		e.buf.WriteString("\n")
+	case ast.Paragraph:
+		for _, line := range n.Children {
+			e.buf.WriteString(strings.Repeat(" ", line.Indent))
+			e.buf.WriteString(e.inlineText(line.Children))
+			e.buf.WriteString("\n")
+		}
+	case ast.Heading:
+		level := n.Level
+		if level > headingMaxLevel {
+			level = headingMaxLevel
+		}
+		mark := headingMark(level)
+		e.buf.WriteString(mark)
+		e.buf.WriteString(" ")
+		e.buf.WriteString(n.Text)
+		e.buf.WriteString(" ")
+		e.buf.WriteString(mark)
+		e.buf.WriteString("\n")
+	case ast.Horizon:
+		if strings.HasPrefix(n.Text, "-") && strings.HasSuffix(n.Text, "-") {
+			e.buf.WriteString(n.Text)
+		} else {
+			e.buf.WriteString("----")
+		}
+		e.buf.WriteString("\n")
+	case ast.Raw:
+		e.lines(n.Lines)
+	case ast.Code:
+		// blank lines around code are part of markup
+		e.buf.WriteString("{{{\n\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("\n}}}\n\n")
+	case ast.Math:
+		e.buf.WriteString("%%%\n")
+		e.lines(n.Lines)
+		e.buf.WriteString("%%%\n\n")
+	case ast.List:
+		e.list(n, 0)
+	case ast.Table:
+		e.table(n)
+	case ast.TOC:
+		e.buf.WriteString("<<toc>>\n\n")
+	case ast.Redirect:
+		e.buf.WriteString("<<redirect ")
+		e.buf.WriteString(n.Name)
+		e.buf.WriteString(">>\n")
+	}
+}
+
+// Emit writes document tree as Nomark text.
+func Emit(doc *ast.Node) string {
+	e := emitter{}
+	for _, n := range doc.Children {
+		e.block(n)
+	}
+	return e.buf.String()
+}
@@ -0,0 +1,23 @@
+package nomark
+
+import "testing"
+
+func TestEmitStable(t *testing.T) {
+	tests := []string{
+		"!!!!! Title !!!!!\n\nThis is **strong** and //em//.\nWikiPage and [[page]].\n",
+		"- one\n- two\n  1. three\n  2. four\n",
+		"| a | b |\n| :-- | --: |\n| c | d |\n",
+		"{{{\n\ncode\n\n}}}\n\n%%%\nx^2\n%%%\n",
+		"text\n\n raw\n\n----\n",
+		"| a |\n|---|\n| 1 | 2 | 3 |\n",
+		"| [[Page|label]] | b |\n",
+	}
+
+	for _, text := range tests {
+		first := Emit(Parse(mockCfg, text))
+		second := Emit(Parse(mockCfg, first))
+		if second != first {
+			t.Errorf("Emit(Parse(%q)) = %q, want %q", first, second, first)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+// SplitSection splits text into text before n-th section,
+// the section and text after it.
+// Sections are numbered from 1 in order of headings
+// and include their subsections.
+func SplitSection(cfg *config.Config, text string, n int) (
+	string, string, string, bool,
+) {
+	outline := ast.Outline(Parse(cfg, text))
+	if n < 1 || n > len(outline) {
+		return "", "", "", false
+	}
+
+	section := outline[n-1]
+	end := len(text)
+	for _, next := range outline[n:] {
+		if next.Level <= section.Level {
+			end = next.Offset
+			break
+		}
+	}
+
+	return text[:section.Offset], text[section.Offset:end], text[end:], true
+}
+
+// ReplaceSection replaces n-th section of text with new section text.
+func ReplaceSection(cfg *config.Config, text string, n int, section string) (
+	string, bool,
+) {
+	before, _, after, ok := SplitSection(cfg, text, n)
+	if !ok {
+		return "", false
+	}
+
+	// keep section separated from succeeding heading
+	if section != "" && !strings.HasSuffix(section, "\n") {
+		section += "\n"
+	}
+	if after != "" && section != "" && !strings.HasSuffix(section, "\n\n") {
+		section += "\n"
+	}
+
+	return before + section + after, true
+}
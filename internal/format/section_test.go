@@ -0,0 +1,52 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/akikareha/himewiki/internal/config"
+)
+
+const sectionText = "!!!!! Title !!!!!\n\nintro\n\n" +
+	"!!!! A !!!!\n\na text\n\n" +
+	"!!! A1 !!!\n\na1 text\n\n" +
+	"!!!! B !!!!\n\nb text\n"
+
+func TestSplitSection(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+		ok   bool
+	}{
+		{"with subsection", 1, "!!!! A !!!!\n\na text\n\n!!! A1 !!!\n\na1 text\n\n", true},
+		{"subsection", 2, "!!! A1 !!!\n\na1 text\n\n", true},
+		{"last", 3, "!!!! B !!!!\n\nb text\n", true},
+		{"zero", 0, "", false},
+		{"out of range", 4, "", false},
+	}
+
+	cfg := &config.Config{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, got, after, ok := SplitSection(cfg, sectionText, tt.n)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("SplitSection(%d) = %q, %v; want %q, %v", tt.n, got, ok, tt.want, tt.ok)
+			}
+			if ok && before+got+after != sectionText {
+				t.Errorf("SplitSection(%d) does not cover text", tt.n)
+			}
+		})
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	cfg := &config.Config{}
+	got, ok := ReplaceSection(cfg, sectionText, 2, "!!! A2 !!!\n\nnew text")
+	want := "!!!!! Title !!!!!\n\nintro\n\n" +
+		"!!!! A !!!!\n\na text\n\n" +
+		"!!! A2 !!!\n\nnew text\n\n" +
+		"!!!! B !!!!\n\nb text\n"
+	if !ok || got != want {
+		t.Errorf("ReplaceSection() = %q, %v; want %q", got, ok, want)
+	}
+}
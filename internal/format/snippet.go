@@ -0,0 +1,80 @@
+package format
+
+import (
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/akikareha/himewiki/internal/config"
+	"github.com/akikareha/himewiki/internal/format/ast"
+)
+
+// Plain returns plain text of wiki text.
+func Plain(cfg *config.Config, text string) string {
+	return ast.Plain(Parse(cfg, text))
+}
+
+// termsRegexp matches any of terms ignoring case, longer first.
+func termsRegexp(terms []string) *regexp.Regexp {
+	var quoted []string
+	for _, term := range terms {
+		if term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) < 1 {
+		return nil
+	}
+	sort.SliceStable(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// Snippet cuts about limit runes of text around first match of terms
+// and returns it as HTML with matches highlighted by mark elements.
+// Whitespace is compressed into single spaces
+// and cut ends are shown by ".." ellipsis.
+func Snippet(text string, terms []string, limit int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	re := termsRegexp(terms)
+
+	start := 0
+	if re != nil {
+		if loc := re.FindStringIndex(string(runes)); loc != nil {
+			start = len([]rune(string(runes)[:loc[0]])) - limit/4
+		}
+	}
+	if start > len(runes)-limit {
+		start = len(runes) - limit
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + limit
+	if end > len(runes) {
+		end = len(runes)
+	}
+	window := string(runes[start:end])
+
+	var buf strings.Builder
+	if start > 0 {
+		buf.WriteString("..")
+	}
+	last := 0
+	if re != nil {
+		for _, loc := range re.FindAllStringIndex(window, -1) {
+			buf.WriteString(template.HTMLEscapeString(window[last:loc[0]]))
+			buf.WriteString("<mark>")
+			buf.WriteString(template.HTMLEscapeString(window[loc[0]:loc[1]]))
+			buf.WriteString("</mark>")
+			last = loc[1]
+		}
+	}
+	buf.WriteString(template.HTMLEscapeString(window[last:]))
+	if end < len(runes) {
+		buf.WriteString("..")
+	}
+	return buf.String()
+}
@@ -0,0 +1,32 @@
+package format
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		limit int
+		want  string
+	}{
+		{"short", "Hello wiki world", []string{"wiki"}, 40, "Hello <mark>wiki</mark> world"},
+		{"case", "Wiki and wiki", []string{"WIKI"}, 40, "<mark>Wiki</mark> and <mark>wiki</mark>"},
+		{"spaces", "a\n\n  b\tc", nil, 40, "a b c"},
+		{"escape", "<b> & wiki", []string{"wiki"}, 40, "&lt;b&gt; &amp; <mark>wiki</mark>"},
+		{"longer first", "edit conflict", []string{"edit", "edit conflict"}, 40, "<mark>edit conflict</mark>"},
+		{"head", "0123456789abcdefghij", []string{"zzz"}, 10, "0123456789.."},
+		{"middle", "0123456789abcdefghij", []string{"c"}, 8, "..ab<mark>c</mark>defgh.."},
+		{"tail", "0123456789abcdefghij", []string{"j"}, 8, "..cdefghi<mark>j</mark>"},
+		{"japanese", "今日は日本語の検索を試します", []string{"検索"}, 8, "..語の<mark>検索</mark>を試しま.."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Snippet(tt.text, tt.terms, tt.limit)
+			if got != tt.want {
+				t.Errorf("Snippet(%q, %q, %d) = %q; want %q", tt.text, tt.terms, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockIdP is local OpenID provider for tests.
+type mockIdP struct {
+	t         *testing.T
+	server    *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	challenge string
+	idToken   string
+}
+
+func b64(buf []byte) string {
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIdP{t: t, rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "rsa1",
+					"use": "sig",
+					"n":   b64(rsaKey.N.Bytes()),
+					"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+				},
+				{
+					"kty": "EC",
+					"kid": "ec1",
+					"crv": "P-256",
+					"x":   b64(ecKey.X.FillBytes(make([]byte, 32))),
+					"y":   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "wiki" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.FormValue("code") != "good-code" ||
+			challenge(r.FormValue("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken,
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// sign makes ID token of claims signed by alg with key of kid.
+func (m *mockIdP) sign(alg, kid string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	if alg == "ES256" {
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, m.ecKey, sum[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, m.rsaKey, crypto.SHA256, sum[:])
+	}
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+func (m *mockIdP) claims() map[string]any {
+	return map[string]any{
+		"iss":                m.server.URL,
+		"sub":                "user-1",
+		"aud":                "wiki",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              "n1",
+		"preferred_username": "alice",
+		"groups":             []string{"staff", "wiki-admins"},
+	}
+}
+
+func newTestProvider(t *testing.T, m *mockIdP) *Provider {
+	t.Helper()
+	p, err := NewProvider(context.Background(), Config{
+		Issuer:       m.server.URL,
+		ClientID:     "wiki",
+		ClientSecret: "secret",
+		RedirectURL:  "https://wiki.example.org/?a=oidccallback",
+	}, m.server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return p
+}
+
+func TestFlow(t *testing.T) {
+	m := newMockIdP(t)
+	p := newTestProvider(t, m)
+
+	authURL, err := url.Parse(p.AuthCodeURL("s1", "n1", "verifier-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := authURL.Query()
+	if authURL.Path != "/authorize" || q.Get("state") != "s1" || q.Get("nonce") != "n1" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "wiki" {
+		t.Fatalf("AuthCodeURL = %s", authURL)
+	}
+	m.challenge = q.Get("code_challenge")
+	m.idToken = m.sign("RS256", "rsa1", m.claims())
+
+	if _, err := p.Exchange(context.Background(), "good-code", "wrong-verifier"); err == nil {
+		t.Fatal("Exchange with wrong verifier succeeded")
+	}
+
+	raw, err := p.Exchange(context.Background(), "good-code", "verifier-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := p.Verify(context.Background(), raw, "n1")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got := claims.String("preferred_username"); got != "alice" {
+		t.Errorf("preferred_username = %q; want %q", got, "alice")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockIdP(t)
+	_, err := NewProvider(context.Background(), Config{
+		Issuer:   m.server.URL + "/other",
+		ClientID: "wiki",
+	}, m.server.Client())
+	if err == nil {
+		t.Fatal("NewProvider with wrong issuer succeeded")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m := newMockIdP(t)
+	p := newTestProvider(t, m)
+
+	tests := []struct {
+		name   string
+		alg    string
+		kid    string
+		modify func(map[string]any)
+		nonce  string
+		tamper bool
+		ok     bool
+	}{
+		{"valid rs256", "RS256", "rsa1", nil, "n1", false, true},
+		{"valid es256", "ES256", "ec1", nil, "n1", false, true},
+		{"aud list", "RS256", "rsa1", func(c map[string]any) {
+			c["aud"] = []string{"other", "wiki"}
+			c["azp"] = "wiki"
+		}, "n1", false, true},
+		{"aud list without azp", "RS256", "rsa1", func(c map[string]any) {
+			c["aud"] = []string{"other", "wiki"}
+		}, "n1", false, false},
+		{"wrong nonce", "RS256", "rsa1", nil, "n2", false, false},
+		{"wrong audience", "RS256", "rsa1", func(c map[string]any) { c["aud"] = "other" }, "n1", false, false},
+		{"wrong issuer", "RS256", "rsa1", func(c map[string]any) { c["iss"] = "https://evil.example" }, "n1", false, false},
+		{"expired", "RS256", "rsa1", func(c map[string]any) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, "n1", false, false},
+		{"no subject", "RS256", "rsa1", func(c map[string]any) { delete(c, "sub") }, "n1", false, false},
+		{"tampered", "RS256", "rsa1", nil, "n1", true, false},
+		{"unknown key", "RS256", "rsa2", nil, "n1", false, false},
+		{"key of other algorithm", "RS256", "ec1", nil, "n1", false, false},
+		{"none algorithm", "none", "rsa1", nil, "n1", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := m.claims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			alg := tt.alg
+			if alg == "none" {
+				alg = "RS256"
+			}
+			raw := m.sign(alg, tt.kid, claims)
+			if tt.alg == "none" {
+				header, _ := json.Marshal(map[string]string{"alg": "none", "kid": tt.kid})
+				payload, _ := json.Marshal(claims)
+				raw = b64(header) + "." + b64(payload) + "."
+			}
+			if tt.tamper {
+				claims["preferred_username"] = "mallory"
+				payload, _ := json.Marshal(claims)
+				parts := strings.Split(raw, ".")
+				raw = parts[0] + "." + b64(payload) + "." + parts[2]
+			}
+
+			_, err := p.Verify(context.Background(), raw, tt.nonce)
+			if tt.ok && err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Verify succeeded; want error")
+			}
+		})
+	}
+}
+
+func TestClaimsValues(t *testing.T) {
+	claims := Claims{
+		"group":  "staff",
+		"groups": []any{"staff", 1.0, "wiki-admins"},
+		"number": 1.0,
+	}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"group", []string{"staff"}},
+		{"groups", []string{"staff", "wiki-admins"}},
+		{"number", nil},
+		{"missing", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := claims.Values(tt.name)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Values(%q) = %q; want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
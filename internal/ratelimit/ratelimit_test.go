@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter(perMinute, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(perMinute, burst)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestNilLimiter(t *testing.T) {
+	l := New(0, 10)
+	if l != nil {
+		t.Fatalf("New(0, 10) = %v; want nil", l)
+	}
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatal("nil limiter denied request")
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		advance   time.Duration // before each request
+		requests  int
+		allowed   int
+		wait      time.Duration // of last denied request
+	}{
+		{"burst", 60, 3, 0, 5, 3, time.Second},
+		{"default burst", 6, 0, 0, 8, 6, 10 * time.Second},
+		{"refill", 60, 1, time.Second, 5, 5, 0},
+		{"slow refill", 60, 1, 500 * time.Millisecond, 4, 2, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.perMinute, tt.burst)
+			allowed := 0
+			var wait time.Duration
+			for i := 0; i < tt.requests; i++ {
+				if i > 0 {
+					clock.t = clock.t.Add(tt.advance)
+				}
+				ok, w := l.Allow("client")
+				if ok {
+					allowed++
+				} else {
+					wait = w
+				}
+			}
+			if allowed != tt.allowed {
+				t.Errorf("allowed = %d; want %d", allowed, tt.allowed)
+			}
+			if wait != tt.wait {
+				t.Errorf("wait = %v; want %v", wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestKeysAreSeparate(t *testing.T) {
+	l, _ := newTestLimiter(60, 1)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("first request of a denied")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("second request of a allowed")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("first request of b denied")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, clock := newTestLimiter(60, 2)
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+
+	clock.t = clock.t.Add(sweepInterval)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets = %d; want 1", len(l.buckets))
+	}
+}
+
+func TestRefund(t *testing.T) {
+	l, _ := newTestLimiter(60, 1)
+	l.Refund("a") // unknown key is ignored
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("first request denied")
+	}
+	l.Refund("a")
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("request after refund denied")
+	}
+	l.Refund("a")
+	l.Refund("a")
+	l.Allow("a")
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("refund exceeded burst")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/url"
 
 	"github.com/akikareha/himewiki/internal/format"
+	"github.com/akikareha/himewiki/internal/format/ast"
 )
 
 //go:embed templates/*.html
@@ -20,6 +21,7 @@ func formatDiff(text string) template.HTML {
 func Render(w http.ResponseWriter, name string, data any) error {
 	funcMap := template.FuncMap{
 		"pathescape": url.PathEscape,
+		"pagepath":   ast.PagePath,
 		"fmtdiff":    formatDiff,
 	}
 	t := template.Must(template.New(name+".html").Funcs(funcMap).ParseFS(tmplFS, "templates/"+name+".html"))
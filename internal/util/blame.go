@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Blame finds which version introduced each line of the last version.
+// Versions are given from oldest to newest.
+// It returns lines of last version and index of version of each line.
+func Blame(versions []string) ([]string, []int) {
+	var lines []string
+	var origins []int
+	for v, text := range versions {
+		next := splitLines(text)
+		nextOrigins := make([]int, len(next))
+		for i := range nextOrigins {
+			nextOrigins[i] = v
+		}
+		matcher := difflib.NewMatcherWithJunk(lines, next, false, nil)
+		for _, block := range matcher.GetMatchingBlocks() {
+			for k := 0; k < block.Size; k++ {
+				nextOrigins[block.B+k] = origins[block.A+k]
+			}
+		}
+		lines, origins = next, nextOrigins
+	}
+
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\n")
+	}
+	return lines, origins
+}
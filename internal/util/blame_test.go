@@ -0,0 +1,58 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlame(t *testing.T) {
+	tests := []struct {
+		name        string
+		versions    []string
+		wantLines   []string
+		wantOrigins []int
+	}{
+		{
+			"none",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"single",
+			[]string{"a\nb\n"},
+			[]string{"a", "b"},
+			[]int{0, 0},
+		},
+		{
+			"changes",
+			[]string{
+				"a\nb\nc\n",
+				"a\nB\nc\n",
+				"x\na\nB\nc\n",
+			},
+			[]string{"x", "a", "B", "c"},
+			[]int{2, 0, 1, 0},
+		},
+		{
+			"line back",
+			[]string{
+				"a\nb\n",
+				"a\n",
+				"a\nb\n",
+			},
+			[]string{"a", "b"},
+			[]int{0, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLines, gotOrigins := Blame(tt.versions)
+			if !reflect.DeepEqual(gotLines, tt.wantLines) || !reflect.DeepEqual(gotOrigins, tt.wantOrigins) {
+				t.Errorf("Blame(%q) = %q, %v; want %q, %v",
+					tt.versions, gotLines, gotOrigins, tt.wantLines, tt.wantOrigins)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"strings"
+
 	"github.com/pmezard/go-difflib/difflib"
 )
 
@@ -15,3 +17,62 @@ func Diff(oldText, newText string) string {
 	text, _ := difflib.GetUnifiedDiffString(diff)
 	return text
 }
+
+// DiffRow is row of side-by-side diff.
+// Line numbers start from 1 and are 0 where side has no line.
+type DiffRow struct {
+	// Op is ' ' for unchanged, '-' for removed, '+' for added,
+	// '~' for changed line, or '@' for gap between hunks.
+	Op    byte
+	OldNo int
+	Old   string
+	NewNo int
+	New   string
+}
+
+// SideBySide compares texts line by line into rows of side-by-side diff.
+// Unchanged lines are shown only around changes like Diff.
+func SideBySide(oldText, newText string) []DiffRow {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+
+	var rows []DiffRow
+	for g, group := range matcher.GetGroupedOpCodes(3) {
+		if g > 0 {
+			rows = append(rows, DiffRow{Op: '@'})
+		}
+		for _, op := range group {
+			i, j := op.I1, op.J1
+			for i < op.I2 || j < op.J2 {
+				row := DiffRow{}
+				if i < op.I2 {
+					row.OldNo = i + 1
+					row.Old = strings.TrimSuffix(a[i], "\n")
+				}
+				if j < op.J2 {
+					row.NewNo = j + 1
+					row.New = strings.TrimSuffix(b[j], "\n")
+				}
+				switch {
+				case op.Tag == 'e':
+					row.Op = ' '
+				case row.OldNo != 0 && row.NewNo != 0:
+					row.Op = '~'
+				case row.OldNo != 0:
+					row.Op = '-'
+				default:
+					row.Op = '+'
+				}
+				if i < op.I2 {
+					i++
+				}
+				if j < op.J2 {
+					j++
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
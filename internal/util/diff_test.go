@@ -0,0 +1,67 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    []DiffRow
+	}{
+		{
+			"same",
+			"a\nb\n",
+			"a\nb\n",
+			nil,
+		},
+		{
+			"changed",
+			"a\nb\nc\n",
+			"a\nB\nc\nd\n",
+			[]DiffRow{
+				{' ', 1, "a", 1, "a"},
+				{'~', 2, "b", 2, "B"},
+				{' ', 3, "c", 3, "c"},
+				{'+', 0, "", 4, "d"},
+			},
+		},
+		{
+			"removed",
+			"a\nb\n",
+			"a\n",
+			[]DiffRow{
+				{' ', 1, "a", 1, "a"},
+				{'-', 2, "b", 0, ""},
+			},
+		},
+		{
+			"hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"0\n2\n3\n4\n5\n6\n7\n8\n9\nX\n",
+			[]DiffRow{
+				{'~', 1, "1", 1, "0"},
+				{' ', 2, "2", 2, "2"},
+				{' ', 3, "3", 3, "3"},
+				{' ', 4, "4", 4, "4"},
+				{'@', 0, "", 0, ""},
+				{' ', 7, "7", 7, "7"},
+				{' ', 8, "8", 8, "8"},
+				{' ', 9, "9", 9, "9"},
+				{'~', 10, "10", 10, "X"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SideBySide(tt.oldText, tt.newText)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SideBySide(%q, %q) = %v; want %v", tt.oldText, tt.newText, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Span is run of text in line of inline diff.
+type Span struct {
+	Text    string
+	Changed bool
+}
+
+// isCharToken tells rune is compared one by one.
+// Words of these scripts are not separated by spaces.
+func isCharToken(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		r == 'ー' || r == '々'
+}
+
+// isWordRune tells rune is part of word separated by spaces.
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)) &&
+		!isCharToken(r)
+}
+
+// tokenize splits line into words, runs of spaces
+// and single other characters.
+func tokenize(line string) []string {
+	var tokens []string
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		end := i + size
+		if isWordRune(r) {
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += size
+			}
+		} else if unicode.IsSpace(r) {
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				end += size
+			}
+		} else if isCharToken(r) {
+			// combining marks belong to preceding character
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !unicode.IsMark(r) {
+					break
+				}
+				end += size
+			}
+		}
+		tokens = append(tokens, line[i:end])
+		i = end
+	}
+	return tokens
+}
+
+func appendSpan(spans []Span, text string, changed bool) []Span {
+	if text == "" {
+		return spans
+	}
+	if len(spans) > 0 && spans[len(spans)-1].Changed == changed {
+		spans[len(spans)-1].Text += text
+		return spans
+	}
+	return append(spans, Span{Text: text, Changed: changed})
+}
+
+// InlineDiff compares removed line and added line by words
+// and returns runs of both lines marking changed ones.
+// Lines with too little in common are not marked at all.
+func InlineDiff(oldLine, newLine string) ([]Span, []Span) {
+	a := tokenize(oldLine)
+	b := tokenize(newLine)
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+	if matcher.Ratio() < 0.5 {
+		return []Span{{Text: oldLine}}, []Span{{Text: newLine}}
+	}
+
+	var oldSpans, newSpans []Span
+	for _, op := range matcher.GetOpCodes() {
+		oldText := strings.Join(a[op.I1:op.I2], "")
+		newText := strings.Join(b[op.J1:op.J2], "")
+		changed := op.Tag != 'e'
+		oldSpans = appendSpan(oldSpans, oldText, changed)
+		newSpans = appendSpan(newSpans, newText, changed)
+	}
+	return oldSpans, newSpans
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"words", "Hello,  world!", []string{"Hello", ",", "  ", "world", "!"}},
+		{"japanese", "今日は晴れ。", []string{"今", "日", "は", "晴", "れ", "。"}},
+		{"mixed", "Goの本", []string{"Go", "の", "本"}},
+		{"katakana", "サーバー", []string{"サ", "ー", "バ", "ー"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenize(tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q; want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldLine string
+		newLine string
+		wantOld []Span
+		wantNew []Span
+	}{
+		{
+			"word",
+			"The quick brown fox.",
+			"The quick red fox.",
+			[]Span{{"The quick ", false}, {"brown", true}, {" fox.", false}},
+			[]Span{{"The quick ", false}, {"red", true}, {" fox.", false}},
+		},
+		{
+			"japanese",
+			"今日は晴れです。",
+			"今日は雨です。",
+			[]Span{{"今日は", false}, {"晴れ", true}, {"です。", false}},
+			[]Span{{"今日は", false}, {"雨", true}, {"です。", false}},
+		},
+		{
+			"insert",
+			"a b",
+			"a x b",
+			[]Span{{"a b", false}},
+			[]Span{{"a ", false}, {"x ", true}, {"b", false}},
+		},
+		{
+			"unrelated",
+			"abc",
+			"xyz",
+			[]Span{{"abc", false}},
+			[]Span{{"xyz", false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOld, gotNew := InlineDiff(tt.oldLine, tt.newLine)
+			if !reflect.DeepEqual(gotOld, tt.wantOld) || !reflect.DeepEqual(gotNew, tt.wantNew) {
+				t.Errorf("InlineDiff(%q, %q) = %v, %v; want %v, %v",
+					tt.oldLine, tt.newLine, gotOld, gotNew, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
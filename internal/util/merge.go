@@ -0,0 +1,102 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// splitLines splits text into lines all ending with line feed.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
+// matchLines maps each line of a to matching line of b or -1.
+func matchLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	// blank lines are too common in wiki text to be junk
+	matcher := difflib.NewMatcherWithJunk(a, b, false, nil)
+	for _, block := range matcher.GetMatchingBlocks() {
+		for k := 0; k < block.Size; k++ {
+			matches[block.A+k] = block.B + k
+		}
+	}
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge3 merges changes from base to current and from base to mine
+// line by line.
+// If both changed same lines differently, conflicting lines are
+// enclosed by conflict markers and false is returned.
+func Merge3(base, current, mine string) (string, bool) {
+	baseLines := splitLines(base)
+	currentLines := splitLines(current)
+	mineLines := splitLines(mine)
+	currentMatches := matchLines(baseLines, currentLines)
+	mineMatches := matchLines(baseLines, mineLines)
+
+	var b strings.Builder
+	clean := true
+	i, c, m := 0, 0, 0
+	for {
+		// find next base line unchanged in both
+		k := i
+		for k < len(baseLines) &&
+			(currentMatches[k] < 0 || mineMatches[k] < 0) {
+			k += 1
+		}
+		currentEnd, mineEnd := len(currentLines), len(mineLines)
+		if k < len(baseLines) {
+			currentEnd, mineEnd = currentMatches[k], mineMatches[k]
+		}
+
+		baseChunk := baseLines[i:k]
+		currentChunk := currentLines[c:currentEnd]
+		mineChunk := mineLines[m:mineEnd]
+		if equalLines(currentChunk, baseChunk) {
+			b.WriteString(strings.Join(mineChunk, ""))
+		} else if equalLines(mineChunk, baseChunk) ||
+			equalLines(mineChunk, currentChunk) {
+			b.WriteString(strings.Join(currentChunk, ""))
+		} else {
+			clean = false
+			b.WriteString("<<<<<<< current\n")
+			b.WriteString(strings.Join(currentChunk, ""))
+			b.WriteString("=======\n")
+			b.WriteString(strings.Join(mineChunk, ""))
+			b.WriteString(">>>>>>> yours\n")
+		}
+
+		if k >= len(baseLines) {
+			break
+		}
+		b.WriteString(baseLines[k])
+		i, c, m = k+1, currentEnd+1, mineEnd+1
+	}
+
+	return b.String(), clean
+}
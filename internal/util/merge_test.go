@@ -0,0 +1,89 @@
+package util
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		current   string
+		mine      string
+		want      string
+		wantClean bool
+	}{
+		{
+			"no changes",
+			"a\nb\nc\n",
+			"a\nb\nc\n",
+			"a\nb\nc\n",
+			"a\nb\nc\n",
+			true,
+		},
+		{
+			"different lines",
+			"a\nb\nc\nd\n",
+			"A\nb\nc\nd\n",
+			"a\nb\nc\nD\n",
+			"A\nb\nc\nD\n",
+			true,
+		},
+		{
+			"same change",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"a\nB\nc\n",
+			"a\nB\nc\n",
+			true,
+		},
+		{
+			"insert and delete",
+			"a\nb\nc\nd\n",
+			"a\nnew\nb\nc\nd\n",
+			"a\nb\nc\n",
+			"a\nnew\nb\nc\n",
+			true,
+		},
+		{
+			"conflict",
+			"a\nb\nc\n",
+			"a\nX\nc\n",
+			"a\nY\nc\n",
+			"a\n<<<<<<< current\nX\n=======\nY\n>>>>>>> yours\nc\n",
+			false,
+		},
+		{
+			"empty base",
+			"",
+			"x\n",
+			"y\n",
+			"<<<<<<< current\nx\n=======\ny\n>>>>>>> yours\n",
+			false,
+		},
+		{
+			"undo change",
+			"a\nB\nc\nd\n",
+			"a\nB\nc\nD\n",
+			"a\nb\nc\nd\n",
+			"a\nb\nc\nD\n",
+			true,
+		},
+		{
+			"no last line feed",
+			"a\nb",
+			"a\nb",
+			"a\nb\nc",
+			"a\nb\nc\n",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotClean := Merge3(tt.base, tt.current, tt.mine)
+			if got != tt.want || gotClean != tt.wantClean {
+				t.Errorf("Merge3(%q, %q, %q) = %q, %v; want %q, %v",
+					tt.base, tt.current, tt.mine, got, gotClean, tt.want, tt.wantClean)
+			}
+		})
+	}
+}
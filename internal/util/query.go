@@ -0,0 +1,70 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query is search query parsed from text such as
+// `wiki "edit conflict" -draft`.
+type Query struct {
+	// Terms are words and phrases which must appear.
+	Terms []string
+
+	// Excludes are words and phrases which must not appear.
+	Excludes []string
+}
+
+// ParseQuery parses words, quoted phrases and exclusions
+// prefixed by minus sign.
+func ParseQuery(text string) Query {
+	var q Query
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		exclude := false
+		if r == '-' && i+1 < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i+1:])
+			if !unicode.IsSpace(next) {
+				exclude = true
+				i += 1
+			}
+		}
+
+		var term string
+		if text[i] == '"' {
+			end := strings.IndexByte(text[i+1:], '"')
+			if end == -1 {
+				term = text[i+1:]
+				i = len(text)
+			} else {
+				term = text[i+1 : i+1+end]
+				i += 1 + end + 1
+			}
+			term = strings.Join(strings.Fields(term), " ")
+		} else {
+			end := strings.IndexFunc(text[i:], unicode.IsSpace)
+			if end == -1 {
+				end = len(text) - i
+			}
+			term = text[i : i+end]
+			i += end
+		}
+
+		if term == "" {
+			continue
+		}
+		if exclude {
+			q.Excludes = append(q.Excludes, term)
+		} else {
+			q.Terms = append(q.Terms, term)
+		}
+	}
+	return q
+}
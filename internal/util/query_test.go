@@ -0,0 +1,32 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Query
+	}{
+		{"empty", "  ", Query{}},
+		{"words", "wiki  page", Query{Terms: []string{"wiki", "page"}}},
+		{"phrase", `"edit  conflict" wiki`, Query{Terms: []string{"edit conflict", "wiki"}}},
+		{"exclude", "wiki -draft", Query{Terms: []string{"wiki"}, Excludes: []string{"draft"}}},
+		{"exclude phrase", `wiki -"old page"`, Query{Terms: []string{"wiki"}, Excludes: []string{"old page"}}},
+		{"minus alone", "a - b", Query{Terms: []string{"a", "-", "b"}}},
+		{"unclosed", `"open phrase`, Query{Terms: []string{"open phrase"}}},
+		{"japanese", "日本語　検索", Query{Terms: []string{"日本語", "検索"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery(%q) = %#v; want %#v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
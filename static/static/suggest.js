@@ -0,0 +1,28 @@
+// Fills datalist of search boxes with page name suggestions.
+document.querySelectorAll('input[data-suggest]').forEach(function (input) {
+	var list = document.getElementById(input.getAttribute('list'));
+	var timer = null;
+	input.addEventListener('input', function () {
+		clearTimeout(timer);
+		var word = input.value;
+		if (word === '') {
+			list.replaceChildren();
+			return;
+		}
+		timer = setTimeout(function () {
+			fetch('/?a=suggest&w=' + encodeURIComponent(word))
+				.then(function (res) { return res.json(); })
+				.then(function (data) {
+					if (input.value !== word) {
+						return;
+					}
+					list.replaceChildren.apply(list, data[1].map(function (name) {
+						var option = document.createElement('option');
+						option.value = name;
+						return option;
+					}));
+				})
+				.catch(function () {});
+		}, 200);
+	});
+});